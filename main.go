@@ -2,22 +2,84 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/aquila/clancy/pricing"
+	"github.com/aquila/clancy/session"
 	"github.com/aquila/clancy/ui"
 	"github.com/aquila/clancy/watcher"
 	tea "github.com/charmbracelet/bubbletea"
 )
 
+// options holds the parsed command line
+type options struct {
+	file        string
+	more        []string // further files, each opened in its own tab
+	help        bool
+	pick        bool
+	hide        []string
+	noHighlight bool
+	only        []string
+	tee         string        // copy of stdin, when reading from a pipe
+	idleTimeout time.Duration // 0 never times out
+}
+
 func main() {
-	filename := findFile()
+	opts, err := parseArgs(os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	prices, err := loadPrices()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading prices: %v\n", err)
+		os.Exit(1)
+	}
+
+	uiOpts := ui.Options{Hide: opts.hide, Only: opts.only, NoHighlight: opts.noHighlight, Prices: prices, IdleTimeout: opts.idleTimeout}
+	if opts.pick {
+		uiOpts.Pick = true
+		run(ui.New("", nil, uiOpts))
+		return
+	}
+
+	if !opts.help && (opts.file == "-" || (opts.file == "" && stdinIsPipe())) {
+		runStdin(opts, uiOpts)
+		return
+	}
+	if opts.tee != "" {
+		fmt.Fprintln(os.Stderr, "Error: --tee only applies when reading from stdin")
+		os.Exit(1)
+	}
+
+	filename := ""
+	if !opts.help {
+		filename = findFile(opts)
+	}
 	if filename == "" {
-		fmt.Fprintln(os.Stderr, "Usage: clancy [file.jsonl]")
+		fmt.Fprintln(os.Stderr, "Usage: clancy [file.jsonl ...]")
 		fmt.Fprintln(os.Stderr, "       clancy --file file.jsonl")
+		fmt.Fprintln(os.Stderr, "       claude -p --output-format stream-json ... | clancy [-]")
+		fmt.Fprintln(os.Stderr, "")
+		fmt.Fprintln(os.Stderr, "If no file specified, looks for *.jsonl in current directory. Several files")
+		fmt.Fprintln(os.Stderr, "open in tabs.")
+		fmt.Fprintln(os.Stderr, "")
+		fmt.Fprintln(os.Stderr, "Options:")
+		fmt.Fprintln(os.Stderr, "  --pick            choose a session from ~/.claude/projects")
+		fmt.Fprintln(os.Stderr, "  --hide kinds      hide event kinds, e.g. --hide thinking,tool_result")
+		fmt.Fprintln(os.Stderr, "  --only kinds      only show event kinds, e.g. --only tool_use (alias: --filter)")
+		fmt.Fprintf(os.Stderr, "                    kinds: %s\n", strings.Join(ui.EventKinds, ", "))
+		fmt.Fprintln(os.Stderr, "  --no-highlight    disable syntax highlighting")
+		fmt.Fprintln(os.Stderr, "  --tee file        when reading stdin, also write the stream to file")
+		fmt.Fprintln(os.Stderr, "  --idle-timeout d  consider the session finished after d without output, e.g. 10m")
 		fmt.Fprintln(os.Stderr, "")
-		fmt.Fprintln(os.Stderr, "If no file specified, looks for *.jsonl in current directory")
+		fmt.Fprintln(os.Stderr, "Cost is estimated from token usage. Override model prices (USD per million")
+		fmt.Fprintln(os.Stderr, "tokens) in the user config directory, e.g. ~/.config/clancy/prices.json")
 		os.Exit(1)
 	}
 
@@ -27,16 +89,81 @@ func main() {
 		os.Exit(1)
 	}
 
+	// A session found in the project directory is replaced by the next one
+	// Claude Code starts there, e.g. after /clear
+	if opts.file == "" {
+		if dir, err := session.CurrentProjectDir(); err == nil && filepath.Dir(filename) == dir {
+			uiOpts.FollowDir = dir
+		}
+	}
+
 	// Create watcher
 	w := watcher.New(filename)
+	w.IdleTimeout = opts.idleTimeout
 	if err := w.Start(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error starting watcher: %v\n", err)
 		os.Exit(1)
 	}
 
-	// Create and run UI
-	model := ui.New(filename, w)
-	p := tea.NewProgram(model, tea.WithAltScreen())
+	// Create and run UI, with a tab per further file
+	model := ui.New(filename, w, uiOpts)
+	for _, file := range opts.more {
+		w := watcher.New(file)
+		w.IdleTimeout = opts.idleTimeout
+		if err := w.Start(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error watching %s: %v\n", file, err)
+			os.Exit(1)
+		}
+		model.AddTab(file, w)
+	}
+	run(model)
+}
+
+// stdinIsPipe reports whether stdin is redirected from a pipe or file rather
+// than attached to a terminal
+func stdinIsPipe() bool {
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice == 0
+}
+
+// runStdin shows the stream piped into stdin, optionally copying it to the
+// tee file. Keys are read from the terminal since stdin carries the stream.
+func runStdin(opts options, uiOpts ui.Options) {
+	var input io.Reader = os.Stdin
+	if opts.tee != "" {
+		f, err := os.Create(opts.tee)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating tee file: %v\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		input = io.TeeReader(os.Stdin, f)
+	}
+
+	w := watcher.NewReader(input)
+	if err := w.Start(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading stdin: %v\n", err)
+		os.Exit(1)
+	}
+	run(ui.New("stdin", w, uiOpts), tea.WithInputTTY())
+}
+
+// loadPrices returns the built-in price table with overrides from the user's
+// config file applied
+func loadPrices() (pricing.Table, error) {
+	path, err := pricing.ConfigPath()
+	if err != nil {
+		return pricing.Default(), nil
+	}
+	return pricing.Load(path)
+}
+
+// run starts the TUI and exits on error
+func run(model ui.Model, opts ...tea.ProgramOption) {
+	p := tea.NewProgram(model, append([]tea.ProgramOption{tea.WithAltScreen()}, opts...)...)
 
 	if _, err := p.Run(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
@@ -44,25 +171,100 @@ func main() {
 	}
 }
 
-func findFile() string {
-	args := os.Args[1:]
+// parseArgs parses command line arguments. Flags may appear before or after
+// the file argument and take their value as the next argument or after "=".
+func parseArgs(args []string) (options, error) {
+	var opts options
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		name, value, hasValue := strings.Cut(arg, "=")
 
-	// Parse args
-	for i, arg := range args {
-		if arg == "--file" || arg == "-f" {
-			if i+1 < len(args) {
-				return args[i+1]
+		// next returns the flag value, consuming the following argument if needed
+		next := func() (string, error) {
+			if hasValue {
+				return value, nil
+			}
+			if i+1 >= len(args) {
+				return "", fmt.Errorf("%s requires a value", name)
 			}
-			return ""
+			i++
+			return args[i], nil
 		}
-		if arg == "--help" || arg == "-h" {
-			return ""
+
+		switch name {
+		case "--file", "-f":
+			v, err := next()
+			if err != nil {
+				return opts, err
+			}
+			opts.file = v
+		case "--tee":
+			v, err := next()
+			if err != nil {
+				return opts, err
+			}
+			opts.tee = v
+		case "--idle-timeout":
+			v, err := next()
+			if err != nil {
+				return opts, err
+			}
+			d, err := time.ParseDuration(v)
+			if err != nil || d < 0 {
+				return opts, fmt.Errorf("invalid --idle-timeout %q, expected a duration such as 10m", v)
+			}
+			opts.idleTimeout = d
+		case "--help", "-h":
+			opts.help = true
+		case "--pick":
+			opts.pick = true
+		case "--no-highlight":
+			opts.noHighlight = true
+		case "--hide", "--only", "--filter":
+			v, err := next()
+			if err != nil {
+				return opts, err
+			}
+			kinds := splitList(v)
+			if err := ui.ValidateKinds(kinds); err != nil {
+				return opts, err
+			}
+			if name == "--hide" {
+				opts.hide = append(opts.hide, kinds...)
+			} else {
+				opts.only = append(opts.only, kinds...)
+			}
+		default:
+			// A lone "-" reads the stream from stdin
+			if strings.HasPrefix(arg, "-") && arg != "-" {
+				return opts, fmt.Errorf("unknown flag %s", arg)
+			}
+			// First non-flag argument is the file; the rest open in tabs
+			if opts.file == "" {
+				opts.file = arg
+			} else {
+				opts.more = append(opts.more, arg)
+			}
 		}
-		// First non-flag argument is the file
-		if arg[0] != '-' {
-			return arg
+	}
+	return opts, nil
+}
+
+// splitList splits a comma-separated flag value, dropping empty items
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
 		}
 	}
+	return items
+}
+
+func findFile(opts options) string {
+	if opts.file != "" {
+		return opts.file
+	}
 
 	// No file specified, first check Claude sessions directory
 	if file := findClaudeSessionFile(); file != "" {
@@ -80,24 +282,11 @@ func findFile() string {
 
 // findClaudeSessionFile looks for the most recent .jsonl in ~/.claude/projects/<project-dir>/
 func findClaudeSessionFile() string {
-	cwd, err := os.Getwd()
-	if err != nil {
-		return ""
-	}
-
-	// Convert path to Claude format: /Users/aquila/Projects/foo -> -Users-aquila-Projects-foo
-	projectDir := strings.ReplaceAll(cwd, "/", "-")
-	if !strings.HasPrefix(projectDir, "-") {
-		projectDir = "-" + projectDir
-	}
-
-	homeDir, err := os.UserHomeDir()
+	claudeProjectPath, err := session.CurrentProjectDir()
 	if err != nil {
 		return ""
 	}
 
-	claudeProjectPath := filepath.Join(homeDir, ".claude", "projects", projectDir)
-
 	// Check if directory exists
 	if _, err := os.Stat(claudeProjectPath); os.IsNotExist(err) {
 		return ""
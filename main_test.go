@@ -130,3 +130,50 @@ func mustParseTime(s string) time.Time {
 	t, _ := time.Parse(time.RFC3339, s)
 	return t
 }
+
+func TestParseArgs(t *testing.T) {
+	opts, err := parseArgs([]string{"session.jsonl", "--hide", "thinking,tool_result", "--only=tool_use"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.file != "session.jsonl" {
+		t.Errorf("expected file session.jsonl, got %q", opts.file)
+	}
+	if len(opts.hide) != 2 || opts.hide[0] != "thinking" || opts.hide[1] != "tool_result" {
+		t.Errorf("unexpected hide kinds %v", opts.hide)
+	}
+	if len(opts.only) != 1 || opts.only[0] != "tool_use" {
+		t.Errorf("unexpected only kinds %v", opts.only)
+	}
+
+	if _, err := parseArgs([]string{"--hide", "bogus"}); err == nil {
+		t.Error("expected error for unknown event kind")
+	}
+	if _, err := parseArgs([]string{"--only"}); err == nil {
+		t.Error("expected error for missing flag value")
+	}
+
+	opts, err = parseArgs([]string{"-", "--tee=run.jsonl"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.file != "-" || opts.tee != "run.jsonl" {
+		t.Errorf("expected stdin with tee, got file %q tee %q", opts.file, opts.tee)
+	}
+
+	opts, err = parseArgs([]string{"--idle-timeout", "10m"})
+	if err != nil || opts.idleTimeout != 10*time.Minute {
+		t.Errorf("expected 10m idle timeout, got %v (%v)", opts.idleTimeout, err)
+	}
+	if _, err := parseArgs([]string{"--idle-timeout=soon"}); err == nil {
+		t.Error("expected error for invalid duration")
+	}
+
+	opts, err = parseArgs([]string{"a.jsonl", "b.jsonl", "c.jsonl"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.file != "a.jsonl" || len(opts.more) != 2 || opts.more[1] != "c.jsonl" {
+		t.Errorf("expected a.jsonl with two more tabs, got %q %v", opts.file, opts.more)
+	}
+}
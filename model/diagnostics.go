@@ -0,0 +1,17 @@
+package model
+
+// Position locates a line in a transcript
+type Position struct {
+	File   string // transcript the line came from
+	Line   int    // 1-based line number, 0 if unknown
+	Offset int64  // byte offset of the line's start
+}
+
+// Diagnostic is a problem met while reading a transcript: a line that isn't
+// valid JSON, an event or content block type clancy doesn't know, or a
+// failure to watch the file
+type Diagnostic struct {
+	Position
+	Kind    string // malformed, unknown event, unknown block or watcher
+	Message string
+}
@@ -1,6 +1,9 @@
 package model
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"time"
+)
 
 // Event represents a Claude Code stream-json event
 type Event struct {
@@ -13,14 +16,71 @@ type Event struct {
 	GitBranch string          `json:"gitBranch,omitempty"`
 	Raw       json.RawMessage `json:"-"`
 
+	// Transcript threading. Subagent lines are marked as a sidechain and,
+	// in newer versions, carry the agent's ID.
+	UUID              string `json:"uuid,omitempty"`
+	ParentUUID        string `json:"parentUuid,omitempty"`
+	LogicalParentUUID string `json:"logicalParentUuid,omitempty"` // set when compaction cuts parentUuid
+	IsSidechain       bool   `json:"isSidechain,omitempty"`
+	AgentID           string `json:"agentId,omitempty"`
+
+	// ToolUseResult is Claude Code's structured copy of a tool result. For
+	// the Task tool it names the agent that ran.
+	ToolUseResult json.RawMessage `json:"toolUseResult,omitempty"`
+
 	// Result fields
-	CostUSD     float64 `json:"cost_usd,omitempty"`
-	DurationMS  int     `json:"duration_ms,omitempty"`
-	NumTurns    int     `json:"num_turns,omitempty"`
+	CostUSD    float64 `json:"cost_usd,omitempty"`
+	DurationMS int     `json:"duration_ms,omitempty"`
+	NumTurns   int     `json:"num_turns,omitempty"`
+	IsError    bool    `json:"is_error,omitempty"`
+	Result     string  `json:"result,omitempty"` // final text or error message
 
 	// System init fields
 	Tools []string `json:"tools,omitempty"`
 	Model string   `json:"model,omitempty"`
+
+	// Compaction: a compact_boundary system event, followed by the summary
+	// that replaces the history as a user message. Summary lines name the
+	// conversation.
+	CompactMetadata  *CompactMetadata `json:"compactMetadata,omitempty"`
+	IsCompactSummary bool             `json:"isCompactSummary,omitempty"`
+	Summary          string           `json:"summary,omitempty"`
+
+	// Streaming event wrapped by --include-partial-messages
+	Event *StreamEvent `json:"event,omitempty"`
+}
+
+// CompactMetadata describes a compaction of the conversation
+type CompactMetadata struct {
+	Trigger   string `json:"trigger"`   // auto or manual
+	PreTokens int    `json:"preTokens"` // context size before compacting
+}
+
+// StreamEvent is a raw Anthropic streaming event, either on its own line or
+// wrapped in a stream_event line
+type StreamEvent struct {
+	Type         string        `json:"type"`                    // message_start, content_block_start, content_block_delta, content_block_stop, message_delta, message_stop, ping, error
+	Message      *Message      `json:"message,omitempty"`       // message_start
+	Index        int           `json:"index"`                   // content block events
+	ContentBlock *ContentBlock `json:"content_block,omitempty"` // content_block_start
+	Delta        *StreamDelta  `json:"delta,omitempty"`         // content_block_delta, message_delta
+	Usage        *Usage        `json:"usage,omitempty"`         // message_delta
+	Error        *StreamError  `json:"error,omitempty"`         // error
+}
+
+// StreamDelta is the payload of a content_block_delta or message_delta event
+type StreamDelta struct {
+	Type        string  `json:"type"` // text_delta, input_json_delta, thinking_delta, signature_delta
+	Text        string  `json:"text,omitempty"`
+	PartialJSON string  `json:"partial_json,omitempty"`
+	Thinking    string  `json:"thinking,omitempty"`
+	StopReason  *string `json:"stop_reason,omitempty"` // message_delta
+}
+
+// StreamError is the payload of an error event
+type StreamError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
 }
 
 // Message is the assistant/user message structure
@@ -36,7 +96,7 @@ type Message struct {
 
 // ContentBlock is an item in message.content array
 type ContentBlock struct {
-	Type      string          `json:"type"` // text, tool_use, tool_result, thinking
+	Type      string          `json:"type"` // text, tool_use, tool_result, thinking, redacted_thinking, server_tool_use, web_search_tool_result, image, document
 	Text      string          `json:"text,omitempty"`
 	ID        string          `json:"id,omitempty"`   // for tool_use
 	Name      string          `json:"name,omitempty"` // for tool_use
@@ -44,38 +104,217 @@ type ContentBlock struct {
 	ToolUseID string          `json:"tool_use_id,omitempty"` // for tool_result
 	Content   json.RawMessage `json:"content,omitempty"`     // for tool_result
 	Thinking  string          `json:"thinking,omitempty"`
+	IsError   bool            `json:"is_error,omitempty"` // for tool_result
+	Source    *MediaSource    `json:"source,omitempty"`   // for image and document
+	Title     string          `json:"title,omitempty"`    // for document
+	Data      string          `json:"data,omitempty"`     // for redacted_thinking
+}
+
+// WebSearchResult is an item of a web_search_tool_result block's content
+type WebSearchResult struct {
+	Type      string `json:"type"` // web_search_result
+	Title     string `json:"title"`
+	URL       string `json:"url"`
+	PageAge   string `json:"page_age,omitempty"`
+	ErrorCode string `json:"error_code,omitempty"` // for web_search_tool_result_error
+}
+
+// MediaSource is the payload of an image or document block
+type MediaSource struct {
+	Type      string `json:"type"` // base64, url or text
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
 }
 
 // Usage contains token usage information
 type Usage struct {
-	InputTokens  int `json:"input_tokens"`
-	OutputTokens int `json:"output_tokens"`
+	InputTokens              int    `json:"input_tokens"`
+	OutputTokens             int    `json:"output_tokens"`
+	CacheCreationInputTokens int    `json:"cache_creation_input_tokens,omitempty"`
+	CacheReadInputTokens     int    `json:"cache_read_input_tokens,omitempty"`
+	ServiceTier              string `json:"service_tier,omitempty"`
+}
+
+// ContextTokens returns the size of the prompt, cached or not, which is how
+// much of the context window the message used
+func (u Usage) ContextTokens() int {
+	return u.InputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens
+}
+
+// Add returns the sum of two usages. The service tier is kept from u unless
+// it's empty.
+func (u Usage) Add(o Usage) Usage {
+	u.InputTokens += o.InputTokens
+	u.OutputTokens += o.OutputTokens
+	u.CacheCreationInputTokens += o.CacheCreationInputTokens
+	u.CacheReadInputTokens += o.CacheReadInputTokens
+	if u.ServiceTier == "" {
+		u.ServiceTier = o.ServiceTier
+	}
+	return u
+}
+
+// MessageUsage is the token usage of one API message. Claude Code writes one
+// line per content block, each repeating the message's usage, so usage is
+// tracked per message ID rather than per line.
+type MessageUsage struct {
+	MessageID string
+	Model     string
+	Usage     Usage
+	Sidechain bool // sent by a subagent rather than the main conversation
+}
+
+// AssistantMessage is an API message assembled from the lines that share its
+// message ID
+type AssistantMessage struct {
+	MessageUsage
+	StopReason string
+	Blocks     []*DisplayEvent // content blocks in order
+}
+
+// HasBlock reports whether the message already holds a content block, so
+// replayed lines don't duplicate it. Tool calls and web search results are
+// matched by ID, text and thinking by content. Redacted thinking and unknown
+// blocks are shown once per message and type.
+func (m *AssistantMessage) HasBlock(block ContentBlock) bool {
+	for _, b := range m.Blocks {
+		switch {
+		case block.Type == "tool_use" || block.Type == "server_tool_use":
+			if b.ToolUse != nil && b.ToolUse.ID == block.ID && block.ID != "" {
+				return true
+			}
+		case block.Type == "web_search_tool_result":
+			if b.ToolUse != nil && b.ToolUse.ID == block.ToolUseID && b.ToolUse.Result != nil {
+				return true
+			}
+		case block.Type == "redacted_thinking":
+			if b.Type == "thinking" && b.Subtype == "redacted" {
+				return true
+			}
+		case block.Type == "text" && b.Type == "assistant" && b.ToolUse == nil:
+			if b.Text == block.Text {
+				return true
+			}
+		case block.Type == "thinking" && b.Type == "thinking":
+			if b.Text == block.Thinking {
+				return true
+			}
+		case b.Type == "unknown":
+			if b.Subtype == block.Type {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 // DisplayEvent is a processed event ready for rendering
 type DisplayEvent struct {
-	Type       string // system, assistant, user, thinking, tool_result, result
-	Text       string
-	ToolUse    *ToolUse
-	ToolResult *ToolResult
-	Model      string
-	Cwd        string
-	Usage      *Usage
-	StopReason string
-	CostUSD    float64
-	NumTurns   int
-	DurationMS int
+	Type        string // system, assistant, user, thinking, tool_result, result, agent, fork, compact, summary
+	MessageID   string // API message the block belongs to, for assistant blocks
+	Text        string
+	ToolUse     *ToolUse
+	ToolResult  *ToolResult
+	Model       string
+	Cwd         string
+	Usage       *Usage
+	StopReason  string
+	CostUSD     float64
+	NumTurns    int
+	DurationMS  int
+	Subtype     string        // result subtype, e.g. success or error_max_turns; compaction trigger
+	PreTokens   int           // context size before a compaction
+	Attachments []*Attachment // images and documents sent with a prompt
+	IsError     bool          // failed result event
+	Partial     bool          // block still streaming; Text or tool input keeps growing
+	Agent       *Agent        // subagent run not spawned by a known tool call, for type "agent"
+}
+
+// Failed reports whether the event is a failed result or a tool call/result
+// flagged with is_error
+func (e *DisplayEvent) Failed() bool {
+	switch {
+	case e.IsError:
+		return true
+	case e.ToolUse != nil:
+		return e.ToolUse.Failed()
+	case e.ToolResult != nil:
+		return e.ToolResult.IsError
+	}
+	return false
 }
 
 // ToolUse represents a tool invocation
 type ToolUse struct {
-	ID    string
-	Name  string
-	Input string // JSON string of input
+	ID        string
+	Name      string
+	Input     string // JSON string of input
+	StartedAt time.Time
+	Result    *ToolResult // set once the matching tool_result arrives
+	Agent     *Agent      // subagent run spawned by a Task call
+}
+
+// Pending reports whether the tool call is still waiting for its result
+func (t *ToolUse) Pending() bool {
+	return t.Result == nil
+}
+
+// Failed reports whether the tool call's result is an error
+func (t *ToolUse) Failed() bool {
+	return t.Result != nil && t.Result.IsError
+}
+
+// Latency returns the time between the call and its result, or zero if unknown
+func (t *ToolUse) Latency() time.Duration {
+	if t.Result == nil || t.StartedAt.IsZero() || t.Result.CompletedAt.IsZero() {
+		return 0
+	}
+	return t.Result.CompletedAt.Sub(t.StartedAt)
 }
 
 // ToolResult represents a tool result
 type ToolResult struct {
-	ToolUseID string
-	Content   string // truncated content
+	ToolUseID   string
+	Content     string // full content; renderers truncate for display
+	IsError     bool
+	CompletedAt time.Time
+	Attachments []*Attachment // images and documents returned, e.g. by Read
+}
+
+// Attachment is an image or document from a message
+type Attachment struct {
+	Kind          string // image or document
+	MediaType     string // e.g. image/png or application/pdf
+	Title         string
+	URL           string // for sources referenced by URL rather than included
+	Data          []byte // decoded content
+	Width, Height int    // image dimensions in pixels, 0 if unknown
+}
+
+// Agent is a subagent run, such as one spawned by the Task tool, assembled
+// from the sidechain lines that belong to it
+type Agent struct {
+	ID         string          // agentId, or the uuid of the run's first line
+	Events     []*DisplayEvent // the run's own events, oldest first
+	Messages   []*AssistantMessage
+	Start, End time.Time // timestamps of the first and latest lines
+	Lines      int       // sidechain lines seen; 0 if only the result named it
+}
+
+// Usage returns the token usage summed over the run's messages
+func (a *Agent) Usage() Usage {
+	var total Usage
+	for _, msg := range a.Messages {
+		total = total.Add(msg.Usage)
+	}
+	return total
+}
+
+// Duration returns the time between the run's first and latest lines
+func (a *Agent) Duration() time.Duration {
+	if a.Start.IsZero() || a.End.IsZero() {
+		return 0
+	}
+	return a.End.Sub(a.Start)
 }
@@ -0,0 +1,147 @@
+package parser
+
+import (
+	"encoding/json"
+	"slices"
+	"time"
+
+	"github.com/aquila/clancy/model"
+)
+
+// agents assembles subagent runs from sidechain lines. Older Claude Code
+// versions write them into the session file, linked to each other through
+// parentUuid; newer ones tag every line with an agentId and write it to an
+// agent-<id>.jsonl file next to the session.
+type agents struct {
+	list   []*model.Agent
+	byID   map[string]*model.Agent
+	byUUID map[string]*model.Agent         // the run each sidechain line belongs to
+	tools  map[*model.Agent]*model.ToolUse // the Task call that spawned each run
+	shown  map[*model.Agent]bool           // runs already nested or emitted
+	tasks  []*model.ToolUse                // Task calls, oldest first
+}
+
+func newAgents() *agents {
+	return &agents{
+		byID:   make(map[string]*model.Agent),
+		byUUID: make(map[string]*model.Agent),
+		tools:  make(map[*model.Agent]*model.ToolUse),
+		shown:  make(map[*model.Agent]bool),
+	}
+}
+
+// isTaskTool reports whether a tool spawns subagents
+func isTaskTool(name string) bool {
+	return name == "Task" || name == "Agent"
+}
+
+// agent returns the run with the given ID, creating it on first sight
+func (a *agents) agent(id string) *model.Agent {
+	agent, ok := a.byID[id]
+	if !ok {
+		agent = &model.Agent{ID: id}
+		a.byID[id] = agent
+		a.list = append(a.list, agent)
+	}
+	return agent
+}
+
+// sidechain files the events of a subagent line under its run instead of the
+// main conversation. The first line of a run nests it under the Task call
+// waiting for it, or, failing that, returns an "agent" event that shows it on
+// its own.
+func (p *Parser) sidechain(event *model.Event, msg *model.AssistantMessage, events []*model.DisplayEvent, at time.Time) []*model.DisplayEvent {
+	a := p.agents
+	var agent *model.Agent
+	switch {
+	case event.AgentID != "":
+		agent = a.agent(event.AgentID)
+	case a.byUUID[event.ParentUUID] != nil && event.ParentUUID != "":
+		agent = a.byUUID[event.ParentUUID]
+	default:
+		agent = a.agent(event.UUID)
+	}
+	if event.UUID != "" {
+		a.byUUID[event.UUID] = agent
+	}
+
+	agent.Lines++
+	if agent.Start.IsZero() {
+		agent.Start = at
+	}
+	agent.End = at
+	if msg != nil && !slices.Contains(agent.Messages, msg) {
+		msg.Sidechain = true
+		agent.Messages = append(agent.Messages, msg)
+	}
+	agent.Events = append(agent.Events, events...)
+
+	if a.shown[agent] {
+		return nil
+	}
+	a.shown[agent] = true
+	if tool := a.waitingTask(agent); tool != nil {
+		a.link(agent, tool)
+		return nil
+	}
+	return []*model.DisplayEvent{{Type: "agent", Agent: agent}}
+}
+
+// waitingTask picks the running Task call a new run most likely belongs to:
+// the one whose prompt matches the run's first message, else the oldest
+func (a *agents) waitingTask(agent *model.Agent) *model.ToolUse {
+	prompt := ""
+	if len(agent.Events) > 0 && agent.Events[0].Type == "user" {
+		prompt = agent.Events[0].Text
+	}
+	var oldest *model.ToolUse
+	for _, tool := range a.tasks {
+		if tool.Agent != nil || !tool.Pending() {
+			continue
+		}
+		if prompt != "" && taskPrompt(tool.Input) == prompt {
+			return tool
+		}
+		if oldest == nil {
+			oldest = tool
+		}
+	}
+	return oldest
+}
+
+// link nests a run under the Task call that spawned it, moving it away from
+// a call it was wrongly guessed to belong to
+func (a *agents) link(agent *model.Agent, tool *model.ToolUse) {
+	if tool.Agent == agent {
+		return
+	}
+	if old := a.tools[agent]; old != nil {
+		old.Agent = nil
+	}
+	tool.Agent = agent
+	a.tools[agent] = tool
+	a.shown[agent] = true
+}
+
+// taskPrompt returns the prompt of a Task call's input
+func taskPrompt(input string) string {
+	var data struct {
+		Prompt string `json:"prompt"`
+	}
+	json.Unmarshal([]byte(input), &data)
+	return data.Prompt
+}
+
+// resultAgentID returns the agent named by a Task call's toolUseResult
+func resultAgentID(raw json.RawMessage) string {
+	var data struct {
+		AgentID string `json:"agentId"`
+	}
+	json.Unmarshal(raw, &data)
+	return data.AgentID
+}
+
+// Agents returns the subagent runs seen so far, in order of first sight
+func (p *Parser) Agents() []*model.Agent {
+	return p.agents.list
+}
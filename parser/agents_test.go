@@ -0,0 +1,77 @@
+package parser
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSidechainNestsUnderTaskCall(t *testing.T) {
+	p := New()
+	events := parseAll(t, p, `{"type":"assistant","uuid":"u1","timestamp":"2025-01-10T10:00:00Z","message":{"id":"msg_1","role":"assistant","content":[{"type":"tool_use","id":"toolu_task","name":"Task","input":{"description":"find tests","prompt":"List the test files"}}]}}
+{"type":"user","isSidechain":true,"uuid":"s1","parentUuid":null,"timestamp":"2025-01-10T10:00:01Z","message":{"role":"user","content":"List the test files"}}
+{"type":"assistant","isSidechain":true,"uuid":"s2","parentUuid":"s1","timestamp":"2025-01-10T10:00:05Z","message":{"id":"msg_s","role":"assistant","content":[{"type":"text","text":"Found 3 files"}],"usage":{"input_tokens":100,"output_tokens":20}}}
+{"type":"user","uuid":"u2","parentUuid":"u1","timestamp":"2025-01-10T10:00:06Z","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_task","content":"Found 3 files"}]}}`)
+
+	if len(events) != 1 || events[0].ToolUse == nil {
+		t.Fatalf("expected only the Task call in the main stream, got %d events", len(events))
+	}
+	agent := events[0].ToolUse.Agent
+	if agent == nil {
+		t.Fatal("expected the sidechain nested under the Task call")
+	}
+	if len(agent.Events) != 2 || agent.Events[1].Text != "Found 3 files" {
+		t.Errorf("unexpected agent events %v", agent.Events)
+	}
+	if u := agent.Usage(); u.InputTokens != 100 || u.OutputTokens != 20 {
+		t.Errorf("unexpected agent usage %+v", u)
+	}
+	if agent.Duration() != 4*time.Second {
+		t.Errorf("expected 4s duration, got %v", agent.Duration())
+	}
+	if usage := p.Usage(); len(usage) != 1 || !usage[0].Sidechain {
+		t.Errorf("expected the subagent message marked as sidechain, got %+v", usage)
+	}
+}
+
+func TestSidechainMatchesTaskByPrompt(t *testing.T) {
+	p := New()
+	events := parseAll(t, p, `{"type":"assistant","message":{"id":"msg_1","role":"assistant","content":[{"type":"tool_use","id":"toolu_a","name":"Task","input":{"prompt":"first job"}},{"type":"tool_use","id":"toolu_b","name":"Task","input":{"prompt":"second job"}}]}}
+{"type":"user","isSidechain":true,"agentId":"b0b0b0b0","message":{"role":"user","content":"second job"}}
+{"type":"user","isSidechain":true,"agentId":"a0a0a0a0","message":{"role":"user","content":"first job"}}`)
+
+	if a := events[0].ToolUse.Agent; a == nil || a.ID != "a0a0a0a0" {
+		t.Errorf("expected first Task to get agent a0a0a0a0, got %+v", a)
+	}
+	if b := events[1].ToolUse.Agent; b == nil || b.ID != "b0b0b0b0" {
+		t.Errorf("expected second Task to get agent b0b0b0b0, got %+v", b)
+	}
+}
+
+func TestTaskResultNamesAgentFile(t *testing.T) {
+	p := New()
+	events := parseAll(t, p, `{"type":"assistant","message":{"id":"msg_1","role":"assistant","content":[{"type":"tool_use","id":"toolu_task","name":"Task","input":{"prompt":"go"}}]}}
+{"type":"user","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_task","content":"done"}]},"toolUseResult":{"status":"completed","agentId":"c0ffee00"}}`)
+
+	agent := events[0].ToolUse.Agent
+	if agent == nil || agent.ID != "c0ffee00" || agent.Lines != 0 {
+		t.Fatalf("expected agent c0ffee00 known only by ID, got %+v", agent)
+	}
+
+	// Lines from the agent's own file fill in the run
+	if got := parseAll(t, p, `{"type":"user","isSidechain":true,"agentId":"c0ffee00","message":{"role":"user","content":"go"}}`); len(got) != 0 {
+		t.Errorf("expected agent file lines kept out of the main stream, got %d", len(got))
+	}
+	if len(agent.Events) != 1 || len(p.Agents()) != 1 {
+		t.Errorf("expected one agent with one event, got %d agents", len(p.Agents()))
+	}
+}
+
+func TestSidechainWithoutTaskShowsOnItsOwn(t *testing.T) {
+	p := New()
+	events := parseAll(t, p, `{"type":"user","isSidechain":true,"agentId":"d00d","message":{"role":"user","content":"Warmup"}}
+{"type":"assistant","isSidechain":true,"agentId":"d00d","message":{"id":"msg_w","role":"assistant","content":[{"type":"text","text":"ready"}]}}`)
+
+	if len(events) != 1 || events[0].Type != "agent" || len(events[0].Agent.Events) != 2 {
+		t.Fatalf("expected a single agent event holding the run, got %+v", events)
+	}
+}
@@ -0,0 +1,42 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	_ "image/gif" // register decoders for image dimensions
+	_ "image/jpeg"
+	_ "image/png"
+
+	"github.com/aquila/clancy/model"
+)
+
+// isMedia reports whether a content block carries an image or document
+func isMedia(block model.ContentBlock) bool {
+	return block.Type == "image" || block.Type == "document"
+}
+
+// attachment decodes an image or document block. Images in a format the
+// standard library knows get their dimensions.
+func attachment(block model.ContentBlock) *model.Attachment {
+	a := &model.Attachment{Kind: block.Type, Title: block.Title}
+	if src := block.Source; src != nil {
+		a.MediaType = src.MediaType
+		a.URL = src.URL
+		switch src.Type {
+		case "base64":
+			a.Data, _ = base64.StdEncoding.DecodeString(src.Data)
+		case "text":
+			a.Data = []byte(src.Data)
+			if a.MediaType == "" {
+				a.MediaType = "text/plain"
+			}
+		}
+	}
+	if a.Kind == "image" && len(a.Data) > 0 {
+		if cfg, _, err := image.DecodeConfig(bytes.NewReader(a.Data)); err == nil {
+			a.Width, a.Height = cfg.Width, cfg.Height
+		}
+	}
+	return a
+}
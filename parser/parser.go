@@ -1,23 +1,103 @@
 package parser
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/aquila/clancy/model"
 )
 
 // Parser processes NDJSON lines from Claude Code stream-json output
-type Parser struct{}
+type Parser struct {
+	// tools indexes tool calls by ID so results can be attached to them
+	tools map[string]*model.ToolUse
+
+	// messages holds one entry per API message, in arrival order. Claude Code
+	// writes a line per content block, each repeating the message ID and its
+	// cumulative usage, so lines are merged by ID.
+	messages     []*model.AssistantMessage
+	messagesByID map[string]*model.AssistantMessage
+
+	// stream assembles the message currently being streamed
+	stream *stream
+
+	// cwd is the session's working directory, from the latest line that has one
+	cwd string
+
+	// agents holds the subagent runs found in sidechain lines
+	agents *agents
+
+	// thread holds the main conversation's lines as a tree, for branches
+	thread *thread
+
+	// compact is the latest compaction boundary, which the summary that
+	// follows it fills in
+	compact *model.DisplayEvent
+
+	// found holds the problems met in the line being parsed
+	found []model.Diagnostic
+}
+
+// quietTypes are line types Claude Code writes for its own bookkeeping, which
+// have nothing to show
+var quietTypes = map[string]bool{
+	"file-history-snapshot": true,
+	"queue-operation":       true,
+}
 
 // New creates a new Parser
 func New() *Parser {
-	return &Parser{}
+	return &Parser{
+		tools:        make(map[string]*model.ToolUse),
+		messagesByID: make(map[string]*model.AssistantMessage),
+		stream:       newStream(),
+		agents:       newAgents(),
+		thread:       newThread(),
+	}
+}
+
+// ParseLineAt parses a line like ParseLine and returns the problems found in
+// it, such as invalid JSON or types clancy doesn't know, located at pos
+func (p *Parser) ParseLineAt(line []byte, pos model.Position) ([]*model.DisplayEvent, []model.Diagnostic) {
+	events, err := p.ParseLine(line)
+	found := p.found
+	p.found = nil
+	if err != nil {
+		found = append(found, model.Diagnostic{Kind: "malformed", Message: fmt.Sprintf("%v in %s", err, snippet(line))})
+	}
+	for i := range found {
+		found[i].Position = pos
+	}
+	return events, found
+}
+
+// diagnose records a problem with the line being parsed
+func (p *Parser) diagnose(kind, format string, args ...any) {
+	p.found = append(p.found, model.Diagnostic{Kind: kind, Message: fmt.Sprintf(format, args...)})
+}
+
+// snippet quotes the start of a line for a diagnostic
+func snippet(line []byte) string {
+	const max = 60
+	if len(line) > max {
+		return strconv.Quote(string(line[:max])) + "…"
+	}
+	return strconv.Quote(string(line))
 }
 
 // ParseLine parses a single JSON line and returns DisplayEvents
 func (p *Parser) ParseLine(line []byte) ([]*model.DisplayEvent, error) {
+	p.found = nil
+	// SSE framing: "event:" lines name the event that the "data:" line repeats
+	if bytes.HasPrefix(line, []byte("event:")) {
+		return nil, nil
+	}
+	line = bytes.TrimPrefix(line, []byte("data:"))
+	line = bytes.TrimSpace(line)
 	if len(line) == 0 {
 		return nil, nil
 	}
@@ -27,11 +107,20 @@ func (p *Parser) ParseLine(line []byte) ([]*model.DisplayEvent, error) {
 		return nil, err
 	}
 	event.Raw = line
+	at := parseTimestamp(event.Timestamp)
+	if event.Cwd != "" {
+		p.cwd = event.Cwd
+	}
 
 	var events []*model.DisplayEvent
+	var msg *model.AssistantMessage
 
 	switch event.Type {
 	case "system":
+		if event.Subtype == "compact_boundary" {
+			events = append(events, p.compactBoundary(&event))
+			break
+		}
 		events = append(events, &model.DisplayEvent{
 			Type:  "system",
 			Model: event.Model,
@@ -43,44 +132,29 @@ func (p *Parser) ParseLine(line []byte) ([]*model.DisplayEvent, error) {
 		if event.Message == nil {
 			return nil, nil
 		}
+		msg = p.mergeMessage(event.Message)
 		blocks := p.parseContent(event.Message.Content)
 		for _, block := range blocks {
-			de := &model.DisplayEvent{
-				Type:  "assistant",
-				Model: event.Message.Model,
-				Usage: event.Message.Usage,
+			if msg.HasBlock(block) {
+				continue // replayed line, or a block that was already streamed
 			}
-			if event.Message.StopReason != nil {
-				de.StopReason = *event.Message.StopReason
+			if (block.Type == "text" && block.Text == "") || (block.Type == "thinking" && block.Thinking == "") {
+				continue
 			}
-
-			switch block.Type {
-			case "text":
-				if block.Text != "" {
-					de.Text = block.Text
-					events = append(events, de)
-				}
-			case "tool_use":
-				inputStr := string(block.Input)
-				de.ToolUse = &model.ToolUse{
-					ID:    block.ID,
-					Name:  block.Name,
-					Input: inputStr,
-				}
+			if de := p.blockEvent(msg, block, at); de != nil {
 				events = append(events, de)
-			case "thinking":
-				if block.Thinking != "" {
-					de.Text = block.Thinking
-					de.Type = "thinking"
-					events = append(events, de)
-				}
 			}
 		}
+		msg.Blocks = append(msg.Blocks, events...)
 
 	case "user":
 		if event.Message == nil {
 			return nil, nil
 		}
+		if event.IsCompactSummary {
+			events = p.compactSummary(event.Message.Content)
+			break
+		}
 		// Check if content is a string (human input) or array (tool results)
 		content := event.Message.Content
 		if len(content) > 0 && content[0] == '"' {
@@ -93,34 +167,93 @@ func (p *Parser) ParseLine(line []byte) ([]*model.DisplayEvent, error) {
 				})
 			}
 		} else {
-			// Array content - tool results
+			// Array content - tool results, or a prompt with attached media
 			blocks := p.parseContent(content)
+			if prompt := mediaPrompt(blocks); prompt != nil {
+				events = append(events, prompt)
+			}
 			for _, block := range blocks {
+				if block.Type != "tool_result" && block.Type != "text" && !isMedia(block) {
+					p.diagnose("unknown block", "%s block in user message", block.Type)
+				}
 				if block.Type == "tool_result" {
-					contentStr := p.extractToolResultContent(block.Content)
-					if len(contentStr) > 500 {
-						contentStr = contentStr[:500] + "..."
+					contentStr, attachments := p.extractToolResultContent(block.Content)
+					result := &model.ToolResult{
+						ToolUseID:   block.ToolUseID,
+						Content:     contentStr,
+						IsError:     block.IsError,
+						CompletedAt: at,
+						Attachments: attachments,
+					}
+					// Attach to the originating call; it renders as one unit
+					if tool, ok := p.tools[block.ToolUseID]; ok {
+						tool.Result = result
+						delete(p.tools, block.ToolUseID)
+						if id := resultAgentID(event.ToolUseResult); id != "" && isTaskTool(tool.Name) {
+							p.agents.link(p.agents.agent(id), tool)
+						}
+						continue
 					}
 					events = append(events, &model.DisplayEvent{
-						Type: "tool_result",
-						ToolResult: &model.ToolResult{
-							ToolUseID: block.ToolUseID,
-							Content:   contentStr,
-						},
+						Type:       "tool_result",
+						ToolResult: result,
 					})
 				}
 			}
 		}
 
+	case "stream_event":
+		if event.Event == nil {
+			return nil, nil
+		}
+		events = p.parseStreamEvent(event.Event, at)
+
+	case "message_start", "content_block_start", "content_block_delta", "content_block_stop",
+		"message_delta", "message_stop", "ping", "error":
+		// Raw API streaming event
+		var se model.StreamEvent
+		if err := json.Unmarshal(line, &se); err != nil {
+			return nil, err
+		}
+		events = p.parseStreamEvent(&se, at)
+
 	case "result":
-		if event.Subtype == "success" {
+		if event.Subtype == "success" && !event.IsError {
 			events = append(events, &model.DisplayEvent{
 				Type:       "result",
+				Subtype:    event.Subtype,
 				Text:       fmt.Sprintf("✓ %d turns | $%.4f | %s", event.NumTurns, event.CostUSD, formatDuration(event.DurationMS)),
 				CostUSD:    event.CostUSD,
 				NumTurns:   event.NumTurns,
 				DurationMS: event.DurationMS,
 			})
+		} else {
+			// error_max_turns, error_during_execution and failed successes
+			subtype := event.Subtype
+			if subtype == "" {
+				subtype = "error"
+			}
+			text := fmt.Sprintf("%s | %d turns | $%.4f | %s", subtype, event.NumTurns, event.CostUSD, formatDuration(event.DurationMS))
+			if event.Result != "" {
+				text += "\n" + event.Result
+			}
+			events = append(events, &model.DisplayEvent{
+				Type:       "result",
+				Subtype:    event.Subtype,
+				IsError:    true,
+				Text:       text,
+				CostUSD:    event.CostUSD,
+				NumTurns:   event.NumTurns,
+				DurationMS: event.DurationMS,
+			})
+		}
+
+	case "summary":
+		if event.Summary != "" {
+			events = append(events, &model.DisplayEvent{
+				Type: "summary",
+				Text: event.Summary,
+			})
 		}
 
 	default:
@@ -130,11 +263,175 @@ func (p *Parser) ParseLine(line []byte) ([]*model.DisplayEvent, error) {
 				Type: event.Type,
 			})
 		}
+		switch {
+		case event.Type == "":
+			p.diagnose("unknown event", "line has no type")
+		case !quietTypes[event.Type]:
+			p.diagnose("unknown event", "%s event", event.Type)
+		}
 	}
 
+	if event.IsSidechain {
+		return p.sidechain(&event, msg, events, at), nil
+	}
+	p.thread.add(&event, events)
 	return events, nil
 }
 
+// compactBoundary converts a compact_boundary system event into a divider
+func (p *Parser) compactBoundary(event *model.Event) *model.DisplayEvent {
+	de := &model.DisplayEvent{Type: "compact"}
+	if meta := event.CompactMetadata; meta != nil {
+		de.Subtype = meta.Trigger
+		de.PreTokens = meta.PreTokens
+	}
+	p.compact = de
+	return de
+}
+
+// compactSummary attaches the summary injected after a compaction to its
+// boundary, so it isn't shown as a prompt the user typed
+func (p *Parser) compactSummary(content json.RawMessage) []*model.DisplayEvent {
+	var parts []string
+	for _, block := range p.parseContent(content) {
+		if block.Type == "text" && block.Text != "" {
+			parts = append(parts, block.Text)
+		}
+	}
+	text := strings.Join(parts, "\n")
+	if p.compact != nil {
+		p.compact.Text = text
+		p.compact = nil
+		return nil
+	}
+	return []*model.DisplayEvent{{Type: "compact", Text: text}}
+}
+
+// mergeMessage returns the message a line belongs to, creating it on first
+// sight. Later lines carry the latest usage and stop reason, so they replace
+// the earlier values instead of adding to them.
+func (p *Parser) mergeMessage(m *model.Message) *model.AssistantMessage {
+	msg, ok := p.messagesByID[m.ID]
+	if !ok || m.ID == "" {
+		msg = &model.AssistantMessage{MessageUsage: model.MessageUsage{MessageID: m.ID, Model: m.Model}}
+		p.messages = append(p.messages, msg)
+		if m.ID != "" {
+			p.messagesByID[m.ID] = msg
+		}
+	}
+	if m.Usage != nil {
+		msg.Usage = *m.Usage
+	}
+	if m.StopReason != nil {
+		msg.StopReason = *m.StopReason
+		for _, block := range msg.Blocks {
+			block.StopReason = msg.StopReason
+		}
+	}
+	return msg
+}
+
+// blockEvent converts a content block of a message into a display event,
+// registering tool calls so their results can attach. Web search results
+// attach to their server tool call and return nil; block types clancy doesn't
+// know become an "unknown" event naming the type.
+func (p *Parser) blockEvent(msg *model.AssistantMessage, block model.ContentBlock, at time.Time) *model.DisplayEvent {
+	de := &model.DisplayEvent{
+		Type:       "assistant",
+		MessageID:  msg.MessageID,
+		Model:      msg.Model,
+		Usage:      &msg.Usage,
+		StopReason: msg.StopReason,
+	}
+	switch block.Type {
+	case "text":
+		de.Text = block.Text
+	case "thinking":
+		de.Type = "thinking"
+		de.Text = block.Thinking
+	case "redacted_thinking":
+		// The content is encrypted; only its presence is worth showing
+		de.Type = "thinking"
+		de.Subtype = "redacted"
+	case "tool_use", "server_tool_use":
+		de.ToolUse = &model.ToolUse{
+			ID:        block.ID,
+			Name:      block.Name,
+			Input:     string(block.Input),
+			StartedAt: at,
+		}
+		if block.ID != "" {
+			p.tools[block.ID] = de.ToolUse
+		}
+		if isTaskTool(block.Name) {
+			p.agents.tasks = append(p.agents.tasks, de.ToolUse)
+		}
+	case "web_search_tool_result":
+		result := &model.ToolResult{ToolUseID: block.ToolUseID, CompletedAt: at}
+		result.Content, result.IsError = webSearchResults(block.Content)
+		if tool, ok := p.tools[block.ToolUseID]; ok {
+			tool.Result = result
+			delete(p.tools, block.ToolUseID)
+			return nil
+		}
+		de = &model.DisplayEvent{Type: "tool_result", ToolResult: result}
+	default:
+		de.Type = "unknown"
+		de.Subtype = block.Type
+		de.Text = block.Type + " block"
+		p.diagnose("unknown block", "%s block in assistant message", block.Type)
+	}
+	return de
+}
+
+// webSearchResults lists the title and URL of each result of a web search,
+// one pair of lines per result. A failed search reports its error code.
+func webSearchResults(raw json.RawMessage) (string, bool) {
+	var failed model.WebSearchResult
+	if json.Unmarshal(raw, &failed) == nil && failed.ErrorCode != "" {
+		return "web search failed: " + failed.ErrorCode, true
+	}
+	var results []model.WebSearchResult
+	json.Unmarshal(raw, &results)
+	var lines []string
+	for _, r := range results {
+		lines = append(lines, r.Title, r.URL)
+	}
+	return strings.Join(lines, "\n"), false
+}
+
+// Cwd returns the session's working directory, or "" if no line named one
+func (p *Parser) Cwd() string {
+	return p.cwd
+}
+
+// Messages returns the assistant messages seen so far with their blocks,
+// oldest first
+func (p *Parser) Messages() []*model.AssistantMessage {
+	return p.messages
+}
+
+// Usage returns the token usage of each API message seen so far, oldest
+// first. Messages without usage are left out.
+func (p *Parser) Usage() []*model.MessageUsage {
+	var usage []*model.MessageUsage
+	for _, msg := range p.messages {
+		if msg.Usage != (model.Usage{}) {
+			usage = append(usage, &msg.MessageUsage)
+		}
+	}
+	return usage
+}
+
+// TotalUsage returns the usage summed over all messages, each counted once
+func (p *Parser) TotalUsage() model.Usage {
+	var total model.Usage
+	for _, msg := range p.messages {
+		total = total.Add(msg.Usage)
+	}
+	return total
+}
+
 // parseContent handles content that can be string or array
 func (p *Parser) parseContent(raw json.RawMessage) []model.ContentBlock {
 	if len(raw) == 0 {
@@ -156,40 +453,84 @@ func (p *Parser) parseContent(raw json.RawMessage) []model.ContentBlock {
 	return nil
 }
 
-// extractToolResultContent handles tool_result content that can be string or object
-func (p *Parser) extractToolResultContent(raw json.RawMessage) string {
+// extractToolResultContent handles tool_result content that can be string,
+// an array of content blocks or an arbitrary object. The full content is kept;
+// truncation is left to the renderer. Images and documents in the array are
+// returned as attachments.
+func (p *Parser) extractToolResultContent(raw json.RawMessage) (string, []*model.Attachment) {
 	if len(raw) == 0 {
-		return ""
+		return "", nil
 	}
 
 	// Try string first
 	var text string
 	if err := json.Unmarshal(raw, &text); err == nil {
-		return text
+		return text, nil
 	}
 
-	// For objects, return compact JSON
+	// Arrays of text and media blocks are split into plain text and attachments
+	var blocks []model.ContentBlock
+	if err := json.Unmarshal(raw, &blocks); err == nil && len(blocks) > 0 {
+		var parts []string
+		var attachments []*model.Attachment
+		known := true
+		for _, block := range blocks {
+			switch {
+			case block.Type == "text":
+				parts = append(parts, block.Text)
+			case isMedia(block):
+				attachments = append(attachments, attachment(block))
+			default:
+				known = false
+			}
+		}
+		if known {
+			return strings.Join(parts, "\n"), attachments
+		}
+	}
+
+	// For anything else, return compact JSON
 	var obj interface{}
 	if err := json.Unmarshal(raw, &obj); err == nil {
 		b, _ := json.Marshal(obj)
-		s := string(b)
-		// Truncate long JSON
-		if len(s) > 300 {
-			s = s[:300] + "..."
-		}
-		return s
+		return string(b), nil
 	}
 
-	return string(raw)
+	return string(raw), nil
 }
 
-// truncateLines truncates content to max lines
-func truncateLines(s string, maxLines int) string {
-	lines := strings.Split(s, "\n")
-	if len(lines) <= maxLines {
-		return s
+// mediaPrompt returns the prompt of a user message that attaches images or
+// documents, such as a pasted screenshot, or nil if there are none
+func mediaPrompt(blocks []model.ContentBlock) *model.DisplayEvent {
+	var parts []string
+	var attachments []*model.Attachment
+	for _, block := range blocks {
+		switch {
+		case block.Type == "text" && block.Text != "":
+			parts = append(parts, block.Text)
+		case isMedia(block):
+			attachments = append(attachments, attachment(block))
+		}
+	}
+	if len(attachments) == 0 {
+		return nil
+	}
+	return &model.DisplayEvent{
+		Type:        "user",
+		Text:        strings.Join(parts, "\n"),
+		Attachments: attachments,
+	}
+}
+
+// parseTimestamp parses an event timestamp, falling back to now for live
+// stream-json output which carries none
+func parseTimestamp(ts string) time.Time {
+	if ts != "" {
+		if t, err := time.Parse(time.RFC3339Nano, ts); err == nil {
+			return t
+		}
 	}
-	return strings.Join(lines[:maxLines], "\n") + "\n..."
+	return time.Now()
 }
 
 // formatDuration formats milliseconds into a human-friendly string
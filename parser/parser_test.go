@@ -0,0 +1,302 @@
+package parser
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aquila/clancy/model"
+)
+
+func TestParseLinePairsToolResultWithToolUse(t *testing.T) {
+	p := New()
+
+	events, err := p.ParseLine([]byte(`{"type":"assistant","timestamp":"2025-01-10T10:00:00.000Z","message":{"role":"assistant","content":[{"type":"tool_use","id":"toolu_1","name":"Read","input":{"file_path":"a.go"}},{"type":"tool_use","id":"toolu_2","name":"Bash","input":{"command":"ls"}}]}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected 2 tool_use events, got %d", len(events))
+	}
+	read, bash := events[0].ToolUse, events[1].ToolUse
+	if !read.Pending() || !bash.Pending() {
+		t.Fatal("expected tool calls to be pending before results arrive")
+	}
+
+	// Results arrive out of order, as with parallel tool calls
+	events, err = p.ParseLine([]byte(`{"type":"user","timestamp":"2025-01-10T10:00:01.500Z","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_2","content":"main.go"}]}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 0 {
+		t.Errorf("expected paired result to emit no standalone event, got %d", len(events))
+	}
+	if bash.Result == nil || bash.Result.Content != "main.go" {
+		t.Fatalf("expected Bash result to be attached, got %+v", bash.Result)
+	}
+	if !read.Pending() {
+		t.Error("expected Read to still be pending")
+	}
+	if got := bash.Latency(); got != 1500*time.Millisecond {
+		t.Errorf("expected latency 1.5s, got %v", got)
+	}
+}
+
+func TestParseLineOrphanToolResult(t *testing.T) {
+	p := New()
+
+	events, err := p.ParseLine([]byte(`{"type":"user","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_missing","content":"output"}]}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Type != "tool_result" {
+		t.Fatalf("expected standalone tool_result event, got %+v", events)
+	}
+	if events[0].ToolResult.Content != "output" {
+		t.Errorf("unexpected content %q", events[0].ToolResult.Content)
+	}
+}
+
+func TestParseLineKeepsFullToolResultContent(t *testing.T) {
+	p := New()
+	long := strings.Repeat("x", 5000)
+
+	line, _ := json.Marshal(map[string]interface{}{
+		"type": "user",
+		"message": map[string]interface{}{
+			"role": "user",
+			"content": []map[string]interface{}{
+				{"type": "tool_result", "tool_use_id": "a", "content": long},
+				{"type": "tool_result", "tool_use_id": "b", "content": []map[string]string{
+					{"type": "text", "text": long},
+					{"type": "text", "text": "tail"},
+				}},
+			},
+		},
+	})
+	events, err := p.ParseLine(line)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+	if got := events[0].ToolResult.Content; got != long {
+		t.Errorf("expected string content untouched, got %d bytes", len(got))
+	}
+	if got := events[1].ToolResult.Content; got != long+"\ntail" {
+		t.Errorf("expected text blocks joined in full, got %d bytes", len(got))
+	}
+}
+
+func TestParseLineToolErrorsAndFailedResults(t *testing.T) {
+	p := New()
+
+	events, _ := p.ParseLine([]byte(`{"type":"assistant","message":{"role":"assistant","content":[{"type":"tool_use","id":"t1","name":"Bash","input":{"command":"false"}}]}}`))
+	tool := events[0].ToolUse
+	p.ParseLine([]byte(`{"type":"user","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":"exit status 1","is_error":true}]}}`))
+	if !tool.Failed() || !events[0].Failed() {
+		t.Error("expected tool call to be marked failed")
+	}
+
+	for _, subtype := range []string{"error_max_turns", "error_during_execution"} {
+		events, err := p.ParseLine([]byte(`{"type":"result","subtype":"` + subtype + `","is_error":true,"num_turns":50,"duration_ms":1200}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(events) != 1 {
+			t.Fatalf("expected %s result to be kept, got %d events", subtype, len(events))
+		}
+		if !events[0].IsError || events[0].Subtype != subtype || !strings.Contains(events[0].Text, subtype) {
+			t.Errorf("unexpected %s event %+v", subtype, events[0])
+		}
+	}
+}
+
+func TestParseLineUsagePerMessageID(t *testing.T) {
+	p := New()
+	lines := []string{
+		// Two content blocks of one message repeat its usage; the last is final
+		`{"type":"assistant","message":{"id":"msg_1","model":"claude-sonnet-4-5","role":"assistant","content":[{"type":"text","text":"hi"}],"usage":{"input_tokens":10,"cache_creation_input_tokens":2000,"cache_read_input_tokens":15000,"output_tokens":1,"service_tier":"standard"}}}`,
+		`{"type":"assistant","message":{"id":"msg_1","model":"claude-sonnet-4-5","role":"assistant","content":[{"type":"tool_use","id":"t1","name":"Bash","input":{}}],"usage":{"input_tokens":10,"cache_creation_input_tokens":2000,"cache_read_input_tokens":15000,"output_tokens":120,"service_tier":"standard"}}}`,
+		`{"type":"assistant","message":{"id":"msg_2","model":"claude-sonnet-4-5","role":"assistant","content":[{"type":"text","text":"done"}],"usage":{"input_tokens":5,"cache_read_input_tokens":17000,"output_tokens":30}}}`,
+	}
+	for _, line := range lines {
+		if _, err := p.ParseLine([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	usage := p.Usage()
+	if len(usage) != 2 {
+		t.Fatalf("expected usage for 2 messages, got %d", len(usage))
+	}
+	first := usage[0].Usage
+	if first.OutputTokens != 120 || first.CacheCreationInputTokens != 2000 || first.ServiceTier != "standard" {
+		t.Errorf("unexpected first message usage %+v", first)
+	}
+
+	total := p.TotalUsage()
+	want := model.Usage{InputTokens: 15, OutputTokens: 150, CacheCreationInputTokens: 2000, CacheReadInputTokens: 32000, ServiceTier: "standard"}
+	if total != want {
+		t.Errorf("expected totals %+v, got %+v", want, total)
+	}
+	if got := usage[1].Usage.ContextTokens(); got != 17005 {
+		t.Errorf("expected context of 17005 tokens, got %d", got)
+	}
+}
+
+func TestParseLineMergesBlocksByMessageID(t *testing.T) {
+	p := New()
+	lines := []string{
+		`{"type":"assistant","message":{"id":"msg_1","model":"claude-opus-4-5","role":"assistant","content":[{"type":"thinking","thinking":"plan"}],"stop_reason":null,"usage":{"input_tokens":3,"output_tokens":1}}}`,
+		`{"type":"assistant","message":{"id":"msg_1","model":"claude-opus-4-5","role":"assistant","content":[{"type":"text","text":"Reading it"}],"stop_reason":null,"usage":{"input_tokens":3,"output_tokens":8}}}`,
+		// A resumed session replays an earlier line
+		`{"type":"assistant","message":{"id":"msg_1","model":"claude-opus-4-5","role":"assistant","content":[{"type":"text","text":"Reading it"}],"stop_reason":null,"usage":{"input_tokens":3,"output_tokens":8}}}`,
+		`{"type":"assistant","message":{"id":"msg_1","model":"claude-opus-4-5","role":"assistant","content":[{"type":"tool_use","id":"t1","name":"Read","input":{}}],"stop_reason":"tool_use","usage":{"input_tokens":3,"output_tokens":42}}}`,
+		`{"type":"assistant","message":{"id":"msg_2","model":"claude-opus-4-5","role":"assistant","content":[{"type":"text","text":"Done"}],"stop_reason":"end_turn","usage":{"input_tokens":5,"output_tokens":2}}}`,
+	}
+	var events []*model.DisplayEvent
+	for _, line := range lines {
+		got, err := p.ParseLine([]byte(line))
+		if err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, got...)
+	}
+
+	if len(events) != 4 {
+		t.Fatalf("expected the replayed block to be dropped, got %d events", len(events))
+	}
+	messages := p.Messages()
+	if len(messages) != 2 {
+		t.Fatalf("expected 2 messages, got %d", len(messages))
+	}
+	first := messages[0]
+	if len(first.Blocks) != 3 || first.Blocks[0].Type != "thinking" || first.Blocks[1].Text != "Reading it" || first.Blocks[2].ToolUse == nil {
+		t.Errorf("unexpected blocks for msg_1: %+v", first.Blocks)
+	}
+	for _, block := range first.Blocks {
+		if block.MessageID != "msg_1" || block.StopReason != "tool_use" || block.Usage.OutputTokens != 42 {
+			t.Errorf("expected block to share the final message state, got %+v", block)
+		}
+	}
+	if total := p.TotalUsage(); total.InputTokens != 8 || total.OutputTokens != 44 {
+		t.Errorf("expected usage counted once per message, got %+v", total)
+	}
+}
+
+func TestCompactBoundaryCarriesSummary(t *testing.T) {
+	p := New()
+	events := parseAll(t, p, `{"type":"summary","summary":"Fix login redirect","leafUuid":"a9"}
+{"type":"user","uuid":"u1","message":{"role":"user","content":"fix the login page"}}
+{"type":"system","subtype":"compact_boundary","uuid":"c1","parentUuid":null,"logicalParentUuid":"u1","content":"Conversation compacted","compactMetadata":{"trigger":"auto","preTokens":155000}}
+{"type":"user","uuid":"u2","parentUuid":"c1","isCompactSummary":true,"message":{"role":"user","content":"This session is being continued from a previous conversation. Summary: login fixed"}}`)
+
+	if len(events) != 3 {
+		t.Fatalf("expected summary, prompt and boundary, got %d events", len(events))
+	}
+	if events[0].Type != "summary" || events[0].Text != "Fix login redirect" {
+		t.Errorf("unexpected summary event %+v", events[0])
+	}
+	boundary := events[2]
+	if boundary.Type != "compact" || boundary.Subtype != "auto" || boundary.PreTokens != 155000 {
+		t.Errorf("unexpected boundary %+v", boundary)
+	}
+	if !strings.Contains(boundary.Text, "Summary: login fixed") {
+		t.Errorf("expected the compaction summary on the boundary, got %q", boundary.Text)
+	}
+}
+
+// tinyPNG is a 2×1 PNG image
+const tinyPNG = "iVBORw0KGgoAAAANSUhEUgAAAAIAAAABCAIAAAB7QOjdAAAAEElEQVR4nGL6z8DAwMAICAAA//8FAgEBAGY4tQAAAABJRU5ErkJggg=="
+
+func TestParseImageAndDocumentBlocks(t *testing.T) {
+	p := New()
+	events := parseAll(t, p, `{"type":"assistant","message":{"role":"assistant","content":[{"type":"tool_use","id":"toolu_1","name":"Read","input":{"file_path":"shot.png"}}]}}
+{"type":"user","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_1","content":[{"type":"image","source":{"type":"base64","media_type":"image/png","data":"`+tinyPNG+`"}}]}]}}
+{"type":"user","message":{"role":"user","content":[{"type":"text","text":"what does this say?"},{"type":"document","title":"notes","source":{"type":"text","data":"hello"}}]}}`)
+
+	if len(events) != 2 {
+		t.Fatalf("expected tool call and prompt, got %d events", len(events))
+	}
+	result := events[0].ToolUse.Result
+	if result == nil || result.Content != "" || len(result.Attachments) != 1 {
+		t.Fatalf("expected an image attachment instead of JSON content, got %+v", result)
+	}
+	if img := result.Attachments[0]; img.Kind != "image" || img.MediaType != "image/png" || img.Width != 2 || img.Height != 1 {
+		t.Errorf("unexpected image %+v", img)
+	}
+
+	prompt := events[1]
+	if prompt.Type != "user" || prompt.Text != "what does this say?" || len(prompt.Attachments) != 1 {
+		t.Fatalf("expected prompt with document, got %+v", prompt)
+	}
+	if doc := prompt.Attachments[0]; doc.Kind != "document" || doc.Title != "notes" || string(doc.Data) != "hello" || doc.MediaType != "text/plain" {
+		t.Errorf("unexpected document %+v", doc)
+	}
+}
+
+func TestParseServerToolAndUnknownBlocks(t *testing.T) {
+	p := New()
+	events := parseAll(t, p, `{"type":"assistant","message":{"id":"msg_1","role":"assistant","content":[{"type":"redacted_thinking","data":"EmwKAhgBEgy3va3pzix"}]}}
+{"type":"assistant","message":{"id":"msg_1","role":"assistant","content":[{"type":"server_tool_use","id":"srvtoolu_1","name":"web_search","input":{"query":"bubbletea images"}}]}}
+{"type":"assistant","message":{"id":"msg_1","role":"assistant","content":[{"type":"web_search_tool_result","tool_use_id":"srvtoolu_1","content":[{"type":"web_search_result","title":"Bubble Tea","url":"https://github.com/charmbracelet/bubbletea","encrypted_content":"x"}]}]}}
+{"type":"assistant","message":{"id":"msg_1","role":"assistant","content":[{"type":"web_search_tool_result","tool_use_id":"srvtoolu_1","content":[{"type":"web_search_result","title":"Bubble Tea","url":"https://github.com/charmbracelet/bubbletea"}]}]}}
+{"type":"assistant","message":{"id":"msg_1","role":"assistant","content":[{"type":"container_upload","file_id":"f1"}]}}`)
+
+	if len(events) != 3 {
+		t.Fatalf("expected marker, search call and fallback, got %d events", len(events))
+	}
+	if events[0].Type != "thinking" || events[0].Subtype != "redacted" || events[0].Text != "" {
+		t.Errorf("unexpected redacted thinking event %+v", events[0])
+	}
+	search := events[1].ToolUse
+	if search == nil || search.Name != "web_search" || search.Result == nil {
+		t.Fatalf("expected the search results to attach to the server tool call, got %+v", events[1])
+	}
+	if search.Result.Content != "Bubble Tea\nhttps://github.com/charmbracelet/bubbletea" {
+		t.Errorf("unexpected search results %q", search.Result.Content)
+	}
+	if events[2].Type != "unknown" || events[2].Subtype != "container_upload" {
+		t.Errorf("unexpected fallback event %+v", events[2])
+	}
+
+	failed := parseAll(t, New(), `{"type":"assistant","message":{"id":"msg_2","role":"assistant","content":[{"type":"web_search_tool_result","tool_use_id":"srvtoolu_9","content":{"type":"web_search_tool_result_error","error_code":"max_uses_exceeded"}}]}}`)
+	if len(failed) != 1 || failed[0].ToolResult == nil || !failed[0].ToolResult.IsError || !strings.Contains(failed[0].ToolResult.Content, "max_uses_exceeded") {
+		t.Errorf("expected an orphan failed search result, got %+v", failed)
+	}
+}
+
+func TestParseLineAtReportsProblems(t *testing.T) {
+	p := New()
+	pos := model.Position{File: "s.jsonl", Line: 7, Offset: 512}
+
+	if _, found := p.ParseLineAt([]byte(`{"type":"user","message":{"role":"user","content":"hi"}}`), pos); len(found) != 0 {
+		t.Errorf("expected no problems with a valid line, got %+v", found)
+	}
+	if _, found := p.ParseLineAt([]byte(`{"type":"file-history-snapshot","messageId":"m1"}`), pos); len(found) != 0 {
+		t.Errorf("expected bookkeeping lines to be quiet, got %+v", found)
+	}
+
+	_, found := p.ParseLineAt([]byte(`{"type":"assistant","message":{"role":"assi`), pos)
+	if len(found) != 1 || found[0].Kind != "malformed" || found[0].Position != pos {
+		t.Fatalf("expected a malformed line at %+v, got %+v", pos, found)
+	}
+	if !strings.Contains(found[0].Message, `{\"type\":\"assistant\"`) {
+		t.Errorf("expected the start of the line in the message, got %q", found[0].Message)
+	}
+
+	if _, found := p.ParseLineAt([]byte(`{"type":"telemetry"}`), pos); len(found) != 1 || found[0].Kind != "unknown event" || found[0].Message != "telemetry event" {
+		t.Errorf("expected an unknown event, got %+v", found)
+	}
+
+	events, found := p.ParseLineAt([]byte(`{"type":"assistant","message":{"id":"m2","role":"assistant","content":[{"type":"text","text":"ok"},{"type":"container_upload"}]}}`), pos)
+	if len(events) != 2 {
+		t.Errorf("expected the known block to still show, got %d events", len(events))
+	}
+	if len(found) != 1 || found[0].Kind != "unknown block" || !strings.Contains(found[0].Message, "container_upload") {
+		t.Errorf("expected an unknown block, got %+v", found)
+	}
+}
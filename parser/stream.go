@@ -0,0 +1,156 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aquila/clancy/model"
+)
+
+// stream assembles the message being streamed from its content block events
+type stream struct {
+	msg    *model.AssistantMessage
+	blocks map[int]*model.DisplayEvent // open blocks by content block index
+	inputs map[int]*strings.Builder    // partial tool input JSON by index
+}
+
+func newStream() *stream {
+	return &stream{
+		blocks: make(map[int]*model.DisplayEvent),
+		inputs: make(map[int]*strings.Builder),
+	}
+}
+
+// parseStreamEvent applies a streaming event. A content block is returned as
+// a display event as soon as it starts; later deltas grow it in place, so the
+// UI shows text token by token.
+func (p *Parser) parseStreamEvent(se *model.StreamEvent, at time.Time) []*model.DisplayEvent {
+	s := p.stream
+
+	switch se.Type {
+	case "message_start":
+		if se.Message == nil {
+			return nil
+		}
+		p.closeStream()
+		p.stream.msg = p.mergeMessage(se.Message)
+
+	case "content_block_start":
+		if se.ContentBlock == nil {
+			return nil
+		}
+		if s.msg == nil {
+			// Joined mid-stream without a message_start
+			s.msg = p.mergeMessage(&model.Message{})
+		}
+		if se.ContentBlock.Type != "text" && se.ContentBlock.Type != "thinking" && s.msg.HasBlock(*se.ContentBlock) {
+			return nil
+		}
+		de := p.blockEvent(s.msg, *se.ContentBlock, at)
+		if de == nil {
+			return nil
+		}
+		de.Partial = true
+		if de.ToolUse != nil {
+			// The start event carries an empty input; the real one is streamed
+			de.ToolUse.Input = ""
+			s.inputs[se.Index] = &strings.Builder{}
+		}
+		s.blocks[se.Index] = de
+		s.msg.Blocks = append(s.msg.Blocks, de)
+		return []*model.DisplayEvent{de}
+
+	case "content_block_delta":
+		de := s.blocks[se.Index]
+		if de == nil || se.Delta == nil {
+			return nil
+		}
+		switch se.Delta.Type {
+		case "text_delta":
+			de.Text += se.Delta.Text
+		case "thinking_delta":
+			de.Text += se.Delta.Thinking
+		case "input_json_delta":
+			if b := s.inputs[se.Index]; b != nil && de.ToolUse != nil {
+				b.WriteString(se.Delta.PartialJSON)
+				de.ToolUse.Input = b.String()
+			}
+		}
+
+	case "content_block_stop":
+		if de := s.blocks[se.Index]; de != nil {
+			closeBlock(de)
+			delete(s.blocks, se.Index)
+			delete(s.inputs, se.Index)
+		}
+
+	case "message_delta":
+		if s.msg == nil {
+			return nil
+		}
+		if se.Usage != nil {
+			s.msg.Usage = mergeUsage(s.msg.Usage, *se.Usage)
+		}
+		if se.Delta != nil && se.Delta.StopReason != nil {
+			s.msg.StopReason = *se.Delta.StopReason
+			for _, block := range s.msg.Blocks {
+				block.StopReason = s.msg.StopReason
+			}
+		}
+
+	case "message_stop":
+		p.closeStream()
+
+	case "error":
+		if se.Error == nil {
+			return nil
+		}
+		p.closeStream()
+		return []*model.DisplayEvent{{
+			Type:    "result",
+			Subtype: se.Error.Type,
+			IsError: true,
+			Text:    fmt.Sprintf("%s | %s", se.Error.Type, se.Error.Message),
+		}}
+	}
+
+	return nil
+}
+
+// closeStream finishes any blocks left open and forgets the current message
+func (p *Parser) closeStream() {
+	for _, de := range p.stream.blocks {
+		closeBlock(de)
+	}
+	p.stream = newStream()
+}
+
+// closeBlock marks a streamed block complete
+func closeBlock(de *model.DisplayEvent) {
+	de.Partial = false
+	if de.ToolUse != nil && de.ToolUse.Input == "" {
+		de.ToolUse.Input = "{}"
+	}
+}
+
+// mergeUsage applies a message_delta usage, whose counts are cumulative and
+// may leave out fields that didn't change
+func mergeUsage(u, delta model.Usage) model.Usage {
+	if delta.InputTokens > 0 {
+		u.InputTokens = delta.InputTokens
+	}
+	if delta.OutputTokens > 0 {
+		u.OutputTokens = delta.OutputTokens
+	}
+	if delta.CacheCreationInputTokens > 0 {
+		u.CacheCreationInputTokens = delta.CacheCreationInputTokens
+	}
+	if delta.CacheReadInputTokens > 0 {
+		u.CacheReadInputTokens = delta.CacheReadInputTokens
+	}
+	if delta.ServiceTier != "" {
+		u.ServiceTier = delta.ServiceTier
+	}
+	return u
+}
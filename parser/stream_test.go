@@ -0,0 +1,98 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aquila/clancy/model"
+)
+
+func parseAll(t *testing.T, p *Parser, lines string) []*model.DisplayEvent {
+	t.Helper()
+	var events []*model.DisplayEvent
+	for _, line := range strings.Split(lines, "\n") {
+		got, err := p.ParseLine([]byte(line))
+		if err != nil {
+			t.Fatalf("%s: %v", line, err)
+		}
+		events = append(events, got...)
+	}
+	return events
+}
+
+func TestParseStreamEventsAccumulateDeltas(t *testing.T) {
+	p := New()
+	events := parseAll(t, p, `{"type":"stream_event","event":{"type":"message_start","message":{"id":"msg_1","model":"claude-sonnet-4-5","role":"assistant","content":[],"usage":{"input_tokens":12,"output_tokens":1}}}}
+{"type":"stream_event","event":{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}}
+{"type":"stream_event","event":{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Let me "}}}`)
+
+	if len(events) != 1 {
+		t.Fatalf("expected the text block to appear when it starts, got %d events", len(events))
+	}
+	text := events[0]
+	if text.Text != "Let me " || !text.Partial {
+		t.Errorf("expected partial text, got %+v", text)
+	}
+
+	events = parseAll(t, p, `{"type":"stream_event","event":{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"check."}}}
+{"type":"stream_event","event":{"type":"content_block_stop","index":0}}
+{"type":"assistant","message":{"id":"msg_1","model":"claude-sonnet-4-5","role":"assistant","content":[{"type":"text","text":"Let me check."}]}}
+{"type":"stream_event","event":{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"t1","name":"Bash","input":{}}}}
+{"type":"stream_event","event":{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"command\":"}}}
+{"type":"stream_event","event":{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"\"ls\"}"}}}
+{"type":"stream_event","event":{"type":"content_block_stop","index":1}}
+{"type":"assistant","message":{"id":"msg_1","model":"claude-sonnet-4-5","role":"assistant","content":[{"type":"tool_use","id":"t1","name":"Bash","input":{"command":"ls"}}]}}
+{"type":"stream_event","event":{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":37}}}
+{"type":"stream_event","event":{"type":"message_stop"}}`)
+
+	if text.Text != "Let me check." || text.Partial {
+		t.Errorf("expected completed text, got %+v", text)
+	}
+	if len(events) != 1 || events[0].ToolUse == nil {
+		t.Fatalf("expected only the streamed tool call, got %d events", len(events))
+	}
+	tool := events[0]
+	if tool.ToolUse.Input != `{"command":"ls"}` || tool.Partial {
+		t.Errorf("unexpected tool call %+v", tool.ToolUse)
+	}
+	if tool.StopReason != "tool_use" || text.StopReason != "tool_use" {
+		t.Error("expected stop reason on all blocks")
+	}
+
+	msgs := p.Messages()
+	if len(msgs) != 1 || len(msgs[0].Blocks) != 2 {
+		t.Fatalf("expected one message with 2 blocks, got %d messages", len(msgs))
+	}
+	if u := msgs[0].Usage; u.InputTokens != 12 || u.OutputTokens != 37 {
+		t.Errorf("unexpected usage %+v", u)
+	}
+
+	// The result attaches to the streamed tool call
+	parseAll(t, p, `{"type":"user","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":"main.go"}]}}`)
+	if tool.ToolUse.Pending() {
+		t.Error("expected result to attach to streamed tool call")
+	}
+}
+
+func TestParseRawSSEEvents(t *testing.T) {
+	p := New()
+	events := parseAll(t, p, `event: message_start
+data: {"type":"message_start","message":{"id":"msg_9","model":"claude-haiku-4-5","role":"assistant","content":[]}}
+
+event: content_block_start
+data: {"type":"content_block_start","index":0,"content_block":{"type":"thinking","thinking":""}}
+data: {"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"hmm"}}
+data: {"type":"ping"}
+data: {"type":"content_block_stop","index":0}
+data: {"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`)
+
+	if len(events) != 2 {
+		t.Fatalf("expected thinking and error events, got %d", len(events))
+	}
+	if events[0].Type != "thinking" || events[0].Text != "hmm" || events[0].Partial {
+		t.Errorf("unexpected thinking event %+v", events[0])
+	}
+	if !events[1].Failed() || !strings.Contains(events[1].Text, "Overloaded") {
+		t.Errorf("unexpected error event %+v", events[1])
+	}
+}
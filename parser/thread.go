@@ -0,0 +1,197 @@
+package parser
+
+import (
+	"fmt"
+
+	"github.com/aquila/clancy/model"
+)
+
+// node is a transcript line in the uuid/parentUuid tree. Rewinding or editing
+// a prompt in Claude Code starts a new child of an earlier line, so a line
+// with several children is a fork.
+type node struct {
+	uuid     string
+	parent   *node
+	children []*node
+
+	// divider marks where a branch through the node leaves its fork. It's
+	// kept so the event stays the same across calls to Thread.
+	divider *model.DisplayEvent
+}
+
+// threadLine is a parsed line with the events it produced, in file order
+type threadLine struct {
+	node   *node // nil for lines without a uuid, which are always shown
+	events []*model.DisplayEvent
+	forks  bool // the line gave its parent another child
+}
+
+// thread records the tree of main conversation lines
+type thread struct {
+	lines  []threadLine
+	nodes  map[string]*node
+	latest *node // the last line added, the tip of the active branch
+	forked bool
+
+	view *view // the branch last threaded
+}
+
+// view is the branch last threaded, kept so lines added since can be
+// placed without threading every line again
+type view struct {
+	tip    string // as passed to Thread
+	leaf   *node
+	onPath map[*node]bool
+	hidden map[*node]bool
+	lines  int // the lines placed so far
+}
+
+func newThread() *thread {
+	return &thread{nodes: make(map[string]*node)}
+}
+
+// add records a line of the main conversation. Compaction starts a new root
+// whose logical parent is the line before it, so that link is followed too.
+func (t *thread) add(event *model.Event, events []*model.DisplayEvent) {
+	if event.UUID == "" {
+		t.lines = append(t.lines, threadLine{events: events})
+		return
+	}
+	forks := false
+	n := t.nodes[event.UUID]
+	if n == nil {
+		n = &node{uuid: event.UUID}
+		t.nodes[event.UUID] = n
+		parentUUID := event.ParentUUID
+		if parentUUID == "" {
+			parentUUID = event.LogicalParentUUID
+		}
+		if parent := t.nodes[parentUUID]; parent != nil && parentUUID != "" {
+			n.parent = parent
+			parent.children = append(parent.children, n)
+			forks = len(parent.children) > 1
+			t.forked = t.forked || forks
+		}
+	}
+	t.latest = n
+	t.lines = append(t.lines, threadLine{node: n, events: events, forks: forks})
+}
+
+// onFork reports whether the node's branch starts at a fork, and which of the
+// alternatives it is
+func (n *node) onFork() (index, count int, ok bool) {
+	if n == nil || n.parent == nil || len(n.parent.children) < 2 {
+		return 0, 0, false
+	}
+	for i, c := range n.parent.children {
+		if c == n {
+			return i + 1, len(n.parent.children), true
+		}
+	}
+	return 0, 0, false
+}
+
+// Forked reports whether the conversation has more than one branch, e.g.
+// after a rewind. Until then events can simply be appended in order.
+func (p *Parser) Forked() bool {
+	return p.thread.forked
+}
+
+// Branches returns the uuids of the tips of alternative branches, in file
+// order. The last one is usually the active branch Claude Code continued.
+func (p *Parser) Branches() []string {
+	var tips []string
+	seen := make(map[*node]bool)
+	for _, line := range p.thread.lines {
+		n := line.node
+		if n == nil || len(n.children) > 0 || seen[n] {
+			continue
+		}
+		seen[n] = true
+		for a := n; a != nil; a = a.parent {
+			if _, _, ok := a.onFork(); ok {
+				tips = append(tips, n.uuid)
+				break
+			}
+		}
+	}
+	return tips
+}
+
+// Thread returns the events of the branch ending at the line with uuid tip,
+// or of the latest branch if tip is "". Lines on other branches are left
+// out, and a divider marks each fork the branch passes through.
+func (p *Parser) Thread(tip string) []*model.DisplayEvent {
+	t := p.thread
+	leaf := t.latest
+	if n := t.nodes[tip]; n != nil {
+		leaf = n
+	}
+	onPath := make(map[*node]bool)
+	for n := leaf; n != nil; n = n.parent {
+		onPath[n] = true
+	}
+
+	// A line is hidden when it branches off the path, or descends from one
+	// that does. Roots are always shown: a line without a parent can't be
+	// told apart from a transcript that isn't threaded at all.
+	hidden := make(map[*node]bool)
+	marked := make(map[*node]bool)
+	var events []*model.DisplayEvent
+	for _, line := range t.lines {
+		n := line.node
+		if n != nil && !onPath[n] && n.parent != nil && (onPath[n.parent] || hidden[n.parent]) {
+			hidden[n] = true
+			continue
+		}
+		if i, count, ok := n.onFork(); ok && onPath[n] && !marked[n] {
+			marked[n] = true
+			if n.divider == nil {
+				n.divider = &model.DisplayEvent{Type: "fork"}
+			}
+			n.divider.Text = fmt.Sprintf("branch %d of %d", i, count)
+			events = append(events, n.divider)
+		}
+		events = append(events, line.events...)
+	}
+	t.view = &view{tip: tip, leaf: leaf, onPath: onPath, hidden: hidden, lines: len(t.lines)}
+	return events
+}
+
+// Extend returns the events that lines parsed since the last call to Thread
+// add to the end of the branch it returned. ok is false when they changed
+// the branch itself, e.g. by forking it again or moving the latest branch
+// elsewhere, so Thread has to be called again. tip is the branch on screen,
+// as passed to Thread.
+func (p *Parser) Extend(tip string) (events []*model.DisplayEvent, ok bool) {
+	t := p.thread
+	v := t.view
+	if v == nil || v.tip != tip {
+		return nil, false
+	}
+	for _, line := range t.lines[v.lines:] {
+		n := line.node
+		switch {
+		case line.forks:
+			return nil, false
+		case n == nil:
+			// Lines without a uuid are always shown
+		case tip == "":
+			// Following the latest branch, which only grows from its leaf
+			if n != v.leaf && n.parent != v.leaf {
+				return nil, false
+			}
+			v.leaf = n
+			v.onPath[n] = true
+		case v.onPath[n] || v.hidden[n]:
+			// A line seen before keeps its place
+		case n.parent != nil && (v.onPath[n.parent] || v.hidden[n.parent]):
+			v.hidden[n] = true
+		}
+		if n == nil || !v.hidden[n] {
+			events = append(events, line.events...)
+		}
+		v.lines++
+	}
+	return events, true
+}
@@ -0,0 +1,103 @@
+package parser
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/aquila/clancy/model"
+)
+
+const rewoundSession = `{"type":"user","uuid":"u1","parentUuid":null,"message":{"role":"user","content":"first prompt"}}
+{"type":"assistant","uuid":"a1","parentUuid":"u1","message":{"id":"msg_1","role":"assistant","content":[{"type":"text","text":"answer one"}]}}
+{"type":"user","uuid":"u2","parentUuid":"a1","message":{"role":"user","content":"second prompt"}}
+{"type":"assistant","uuid":"a2","parentUuid":"u2","message":{"id":"msg_2","role":"assistant","content":[{"type":"text","text":"answer two"}]}}
+{"type":"user","uuid":"u3","parentUuid":"a1","message":{"role":"user","content":"edited second prompt"}}
+{"type":"assistant","uuid":"a3","parentUuid":"u3","message":{"id":"msg_3","role":"assistant","content":[{"type":"text","text":"answer three"}]}}`
+
+func texts(events []*model.DisplayEvent) []string {
+	var out []string
+	for _, e := range events {
+		out = append(out, e.Text)
+	}
+	return out
+}
+
+func TestThreadShowsLatestBranch(t *testing.T) {
+	p := New()
+	parseAll(t, p, rewoundSession)
+
+	if !p.Forked() {
+		t.Fatal("expected the rewind to fork the conversation")
+	}
+	if branches := p.Branches(); !slices.Equal(branches, []string{"a2", "a3"}) {
+		t.Errorf("expected branches a2 and a3, got %v", branches)
+	}
+
+	want := []string{"first prompt", "answer one", "branch 2 of 2", "edited second prompt", "answer three"}
+	if got := texts(p.Thread("")); !slices.Equal(got, want) {
+		t.Errorf("latest branch:\n got %q\nwant %q", got, want)
+	}
+	want = []string{"first prompt", "answer one", "branch 1 of 2", "second prompt", "answer two"}
+	if got := texts(p.Thread("a2")); !slices.Equal(got, want) {
+		t.Errorf("first branch:\n got %q\nwant %q", got, want)
+	}
+}
+
+func TestThreadFollowsCompactionAndUnthreadedLines(t *testing.T) {
+	p := New()
+	parseAll(t, p, `{"type":"user","uuid":"u1","message":{"role":"user","content":"one"}}
+{"type":"user","uuid":"u2","message":{"role":"user","content":"two"}}
+{"type":"user","uuid":"u3","parentUuid":null,"logicalParentUuid":"u2","message":{"role":"user","content":"three"}}
+{"type":"user","uuid":"u4","parentUuid":"u2","message":{"role":"user","content":"four"}}`)
+
+	// u3 and u4 both continue u2, so the compacted line counts as a branch
+	if !p.Forked() {
+		t.Fatal("expected a fork through the logical parent")
+	}
+	want := []string{"one", "two", "branch 2 of 2", "four"}
+	if got := texts(p.Thread("")); !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	p = New()
+	events := parseAll(t, p, `{"type":"user","uuid":"x1","message":{"role":"user","content":"one"}}
+{"type":"user","uuid":"x2","message":{"role":"user","content":"two"}}`)
+	if p.Forked() || len(events) != 2 || len(p.Thread("")) != 2 {
+		t.Errorf("expected lines without parents shown in order")
+	}
+}
+
+func TestExtendAppendsUntilTheBranchChanges(t *testing.T) {
+	p := New()
+	parseAll(t, p, rewoundSession)
+	events := p.Thread("")
+	divider := events[2]
+
+	// Growing the latest branch only adds the new line's events
+	parseAll(t, p, `{"type":"user","uuid":"u4","parentUuid":"a3","message":{"role":"user","content":"third prompt"}}
+{"type":"summary","summary":"no uuid"}`)
+	added, ok := p.Extend("")
+	if !ok || !slices.Equal(texts(added), []string{"third prompt", "no uuid"}) {
+		t.Fatalf("expected the new lines appended, got %q (ok %v)", texts(added), ok)
+	}
+
+	// Rewinding again forks, so the branch has to be threaded again with
+	// the same divider, now counting three branches
+	parseAll(t, p, `{"type":"user","uuid":"u5","parentUuid":"a1","message":{"role":"user","content":"third try"}}`)
+	if _, ok := p.Extend(""); ok {
+		t.Fatal("expected a new fork to need threading again")
+	}
+	events = p.Thread("a3")
+	if events[2] != divider || divider.Text != "branch 2 of 3" {
+		t.Errorf("expected the divider kept and relabelled, got %q", events[2].Text)
+	}
+
+	// Lines on the latest branch stay hidden while an older one is on screen
+	parseAll(t, p, `{"type":"user","uuid":"u6","parentUuid":"u5","message":{"role":"user","content":"hidden"}}`)
+	if added, ok := p.Extend("a3"); !ok || len(added) != 0 {
+		t.Errorf("expected nothing added to branch a3, got %q (ok %v)", texts(added), ok)
+	}
+	if _, ok := p.Extend(""); ok {
+		t.Error("expected another branch than the one threaded to need threading")
+	}
+}
@@ -0,0 +1,97 @@
+package pricing
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aquila/clancy/model"
+)
+
+// Price is what a model charges, in USD per million tokens
+type Price struct {
+	Input      float64 `json:"input"`
+	Output     float64 `json:"output"`
+	CacheWrite float64 `json:"cache_write"`
+	CacheRead  float64 `json:"cache_read"`
+}
+
+// Table maps model name prefixes to prices. Model IDs carry a date suffix,
+// e.g. claude-sonnet-4-5-20250929, so lookups use the longest matching prefix.
+type Table map[string]Price
+
+// Default returns the built-in price table
+func Default() Table {
+	return Table{
+		"claude-opus-4-5":   {Input: 5, Output: 25, CacheWrite: 6.25, CacheRead: 0.50},
+		"claude-opus-4":     {Input: 15, Output: 75, CacheWrite: 18.75, CacheRead: 1.50},
+		"claude-sonnet-4":   {Input: 3, Output: 15, CacheWrite: 3.75, CacheRead: 0.30},
+		"claude-haiku-4-5":  {Input: 1, Output: 5, CacheWrite: 1.25, CacheRead: 0.10},
+		"claude-3-opus":     {Input: 15, Output: 75, CacheWrite: 18.75, CacheRead: 1.50},
+		"claude-3-7-sonnet": {Input: 3, Output: 15, CacheWrite: 3.75, CacheRead: 0.30},
+		"claude-3-5-sonnet": {Input: 3, Output: 15, CacheWrite: 3.75, CacheRead: 0.30},
+		"claude-3-5-haiku":  {Input: 0.80, Output: 4, CacheWrite: 1, CacheRead: 0.08},
+		"claude-3-haiku":    {Input: 0.25, Output: 1.25, CacheWrite: 0.30, CacheRead: 0.03},
+	}
+}
+
+// ConfigPath returns the price override file, e.g. ~/.config/clancy/prices.json
+func ConfigPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "clancy", "prices.json"), nil
+}
+
+// Load returns the built-in table with entries from the JSON file at path
+// added or replaced. A missing file is not an error.
+func Load(path string) (Table, error) {
+	table := Default()
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return table, nil
+	}
+	if err != nil {
+		return table, err
+	}
+	var overrides Table
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return table, fmt.Errorf("%s: %w", path, err)
+	}
+	for name, price := range overrides {
+		table[name] = price
+	}
+	return table, nil
+}
+
+// Lookup finds the price of a model by its longest matching prefix. The
+// "[1m]" suffix Claude Code adds to long context variants is ignored.
+func (t Table) Lookup(modelName string) (Price, bool) {
+	name := strings.TrimSuffix(strings.ToLower(modelName), "[1m]")
+	var best Price
+	bestLen := -1
+	for prefix, price := range t {
+		if strings.HasPrefix(name, prefix) && len(prefix) > bestLen {
+			best, bestLen = price, len(prefix)
+		}
+	}
+	return best, bestLen >= 0
+}
+
+// Cost estimates the cost of a message in USD. It reports false when the
+// model has no price.
+func (t Table) Cost(modelName string, u model.Usage) (float64, bool) {
+	p, ok := t.Lookup(modelName)
+	if !ok {
+		return 0, false
+	}
+	cost := float64(u.InputTokens)*p.Input +
+		float64(u.OutputTokens)*p.Output +
+		float64(u.CacheCreationInputTokens)*p.CacheWrite +
+		float64(u.CacheReadInputTokens)*p.CacheRead
+	return cost / 1_000_000, true
+}
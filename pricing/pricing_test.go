@@ -0,0 +1,63 @@
+package pricing
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aquila/clancy/model"
+)
+
+func TestCostUsesLongestPrefix(t *testing.T) {
+	table := Default()
+	u := model.Usage{InputTokens: 1_000_000, OutputTokens: 100_000, CacheCreationInputTokens: 200_000, CacheReadInputTokens: 2_000_000}
+
+	tests := []struct {
+		model string
+		want  float64
+	}{
+		// 15 + 7.5 + 3.75 + 3
+		{"claude-opus-4-1-20250805", 29.25},
+		// 5 + 2.5 + 1.25 + 1
+		{"claude-opus-4-5-20251101", 9.75},
+		// 3 + 1.5 + 0.75 + 0.6
+		{"claude-sonnet-4-5-20250929[1m]", 5.85},
+	}
+	for _, tt := range tests {
+		got, ok := table.Cost(tt.model, u)
+		if !ok || math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: expected $%.4f, got $%.4f (ok=%v)", tt.model, tt.want, got, ok)
+		}
+	}
+
+	if _, ok := table.Cost("gpt-4o", u); ok {
+		t.Error("expected unknown model to have no price")
+	}
+}
+
+func TestLoadOverrides(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prices.json")
+	overrides := `{"claude-sonnet-4": {"input": 6, "output": 22.5, "cache_write": 7.5, "cache_read": 0.6}, "my-proxy-model": {"input": 1, "output": 2}}`
+	if err := os.WriteFile(path, []byte(overrides), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	table, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := table.Lookup("claude-sonnet-4-5-20250929"); p.Input != 6 || p.CacheRead != 0.6 {
+		t.Errorf("expected overridden sonnet price, got %+v", p)
+	}
+	if _, ok := table.Lookup("my-proxy-model"); !ok {
+		t.Error("expected added model to be priced")
+	}
+	if _, ok := table.Lookup("claude-opus-4-1"); !ok {
+		t.Error("expected built-in prices to be kept")
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.json")); err != nil {
+		t.Errorf("expected missing file to fall back to defaults, got %v", err)
+	}
+}
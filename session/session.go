@@ -0,0 +1,228 @@
+package session
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aquila/clancy/model"
+)
+
+// Info summarizes a saved Claude Code session transcript
+type Info struct {
+	Path        string
+	Project     string // encoded project directory name, e.g. -Users-aquila-Projects-foo
+	FirstPrompt string
+	StartTime   time.Time
+	ModTime     time.Time
+	Messages    int // human prompts and assistant messages
+	Model       string
+	GitBranch   string
+	CostUSD     float64                // cost reported by result events
+	Usage       map[string]model.Usage // token usage per model
+}
+
+// ID returns the session ID, taken from the transcript file name
+func (i Info) ID() string {
+	return strings.TrimSuffix(filepath.Base(i.Path), ".jsonl")
+}
+
+// ProjectsDir returns ~/.claude/projects, where Claude Code saves sessions
+func ProjectsDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".claude", "projects"), nil
+}
+
+// EncodeProjectPath converts a path to Claude's project directory name:
+// /Users/aquila/Projects/foo -> -Users-aquila-Projects-foo
+func EncodeProjectPath(path string) string {
+	encoded := strings.ReplaceAll(path, "/", "-")
+	if !strings.HasPrefix(encoded, "-") {
+		encoded = "-" + encoded
+	}
+	return encoded
+}
+
+// CurrentProjectDir returns the session directory for the working directory
+func CurrentProjectDir() (string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	projectsDir, err := ProjectsDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(projectsDir, EncodeProjectPath(cwd)), nil
+}
+
+// List summarizes every session in a project directory, newest first
+func List(projectDir string) ([]Info, error) {
+	matches, err := filepath.Glob(filepath.Join(projectDir, "*.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+
+	var infos []Info
+	for _, path := range matches {
+		info, err := Scan(path)
+		if err != nil {
+			continue
+		}
+		infos = append(infos, info)
+	}
+	sortNewestFirst(infos)
+	return infos, nil
+}
+
+// ListAll summarizes the sessions of every project, newest first
+func ListAll(projectsDir string) ([]Info, error) {
+	entries, err := os.ReadDir(projectsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var infos []Info
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		projectInfos, err := List(filepath.Join(projectsDir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		infos = append(infos, projectInfos...)
+	}
+	sortNewestFirst(infos)
+	return infos, nil
+}
+
+func sortNewestFirst(infos []Info) {
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].ModTime.After(infos[j].ModTime)
+	})
+}
+
+// Scan reads a transcript and collects its summary
+func Scan(path string) (Info, error) {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return Info{}, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return Info{}, err
+	}
+	defer file.Close()
+
+	info := Info{
+		Path:    path,
+		Project: filepath.Base(filepath.Dir(path)),
+		ModTime: stat.ModTime(),
+		Usage:   make(map[string]model.Usage),
+	}
+
+	// Streamed assistant lines repeat the message ID, so count messages once
+	// and keep the last usage seen for each
+	seen := make(map[string]bool)
+	usage := make(map[string]model.MessageUsage)
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		var event model.Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			continue
+		}
+
+		if info.StartTime.IsZero() && event.Timestamp != "" {
+			if t, err := time.Parse(time.RFC3339Nano, event.Timestamp); err == nil {
+				info.StartTime = t
+			}
+		}
+		if info.GitBranch == "" {
+			info.GitBranch = event.GitBranch
+		}
+
+		switch event.Type {
+		case "user":
+			// Tool results and injected command output come back as user
+			// lines too; only prompts typed by the human count
+			if event.Message == nil || event.IsCompactSummary {
+				continue
+			}
+			if prompt := promptText(event.Message.Content); prompt != "" {
+				info.Messages++
+				if info.FirstPrompt == "" {
+					info.FirstPrompt = prompt
+				}
+			}
+		case "assistant":
+			if event.Message == nil {
+				continue
+			}
+			if u := event.Message.Usage; u != nil {
+				mu := model.MessageUsage{MessageID: event.Message.ID, Model: event.Message.Model, Usage: *u}
+				if mu.MessageID != "" {
+					usage[mu.MessageID] = mu
+				} else {
+					info.Usage[mu.Model] = info.Usage[mu.Model].Add(mu.Usage)
+				}
+			}
+			if id := event.Message.ID; id != "" {
+				if seen[id] {
+					continue
+				}
+				seen[id] = true
+			}
+			info.Messages++
+			if info.Model == "" {
+				info.Model = event.Message.Model
+			}
+		case "system":
+			if info.Model == "" {
+				info.Model = event.Model
+			}
+		case "result":
+			info.CostUSD += event.CostUSD
+		}
+	}
+	if info.StartTime.IsZero() {
+		info.StartTime = info.ModTime
+	}
+	for _, mu := range usage {
+		info.Usage[mu.Model] = info.Usage[mu.Model].Add(mu.Usage)
+	}
+	return info, scanner.Err()
+}
+
+// promptText returns the text of a human prompt, or "" for tool results and
+// injected command output
+func promptText(content json.RawMessage) string {
+	var text string
+	if err := json.Unmarshal(content, &text); err != nil {
+		var blocks []model.ContentBlock
+		if err := json.Unmarshal(content, &blocks); err != nil {
+			return ""
+		}
+		for _, block := range blocks {
+			if block.Type == "text" {
+				text = block.Text
+				break
+			}
+		}
+	}
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "<") {
+		// <command-name>, <local-command-stdout> and similar wrappers
+		return ""
+	}
+	return text
+}
@@ -0,0 +1,107 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const transcript = `{"type":"user","message":{"role":"user","content":"<command-name>/clear</command-name>"},"timestamp":"2025-01-10T10:00:00Z","gitBranch":"main"}
+{"type":"user","message":{"role":"user","content":"Fix the flaky watcher test"},"timestamp":"2025-01-10T10:00:05Z","gitBranch":"main"}
+{"type":"assistant","message":{"id":"msg_1","role":"assistant","model":"claude-opus-4-5-20251101","content":[{"type":"text","text":"Looking"}]}}
+{"type":"assistant","message":{"id":"msg_1","role":"assistant","model":"claude-opus-4-5-20251101","content":[{"type":"tool_use","id":"t1","name":"Read","input":{}}]}}
+{"type":"user","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":"ok"}]}}
+not json
+{"type":"result","subtype":"success","cost_usd":0.25}
+`
+
+func TestScan(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "7f3a9c.jsonl")
+	if err := os.WriteFile(path, []byte(transcript), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := Scan(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.FirstPrompt != "Fix the flaky watcher test" {
+		t.Errorf("unexpected first prompt %q", info.FirstPrompt)
+	}
+	if want := time.Date(2025, 1, 10, 10, 0, 0, 0, time.UTC); !info.StartTime.Equal(want) {
+		t.Errorf("expected start %v, got %v", want, info.StartTime)
+	}
+	// One prompt plus one assistant message split over two lines; the
+	// command output and the tool result aren't messages
+	if info.Messages != 2 {
+		t.Errorf("expected 2 messages, got %d", info.Messages)
+	}
+	if info.Model != "claude-opus-4-5-20251101" {
+		t.Errorf("unexpected model %q", info.Model)
+	}
+	if info.GitBranch != "main" {
+		t.Errorf("unexpected branch %q", info.GitBranch)
+	}
+	if info.CostUSD != 0.25 {
+		t.Errorf("unexpected cost %v", info.CostUSD)
+	}
+	if info.ID() != "7f3a9c" {
+		t.Errorf("unexpected ID %q", info.ID())
+	}
+}
+
+func TestListAllNewestFirst(t *testing.T) {
+	projects := t.TempDir()
+	for i, name := range []string{"-proj-a", "-proj-b"} {
+		dir := filepath.Join(projects, name)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(dir, "s.jsonl")
+		if err := os.WriteFile(path, []byte(transcript), 0644); err != nil {
+			t.Fatal(err)
+		}
+		mod := time.Date(2025, 1, 1+i, 0, 0, 0, 0, time.UTC)
+		os.Chtimes(path, mod, mod)
+	}
+
+	infos, err := ListAll(projects)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 2 {
+		t.Fatalf("expected 2 sessions, got %d", len(infos))
+	}
+	if !strings.HasSuffix(infos[0].Project, "proj-b") {
+		t.Errorf("expected newest project first, got %s", infos[0].Project)
+	}
+}
+
+func TestScanUsageKeepsLastLinePerMessage(t *testing.T) {
+	lines := `{"type":"assistant","message":{"id":"msg_1","role":"assistant","model":"claude-sonnet-4-5","content":[{"type":"text","text":"a"}],"usage":{"input_tokens":10,"cache_read_input_tokens":1000,"output_tokens":1}}}
+{"type":"assistant","message":{"id":"msg_1","role":"assistant","model":"claude-sonnet-4-5","content":[{"type":"text","text":"b"}],"usage":{"input_tokens":10,"cache_read_input_tokens":1000,"output_tokens":40}}}
+{"type":"assistant","message":{"id":"msg_2","role":"assistant","model":"claude-haiku-4-5","content":[{"type":"text","text":"c"}],"usage":{"input_tokens":5,"output_tokens":7}}}
+`
+	path := filepath.Join(t.TempDir(), "s.jsonl")
+	if err := os.WriteFile(path, []byte(lines), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := Scan(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(info.Usage) != 2 {
+		t.Fatalf("expected usage for 2 models, got %v", info.Usage)
+	}
+	if u := info.Usage["claude-sonnet-4-5"]; u.OutputTokens != 40 || u.CacheReadInputTokens != 1000 {
+		t.Errorf("unexpected sonnet usage %+v", u)
+	}
+	if u := info.Usage["claude-haiku-4-5"]; u.InputTokens != 5 || u.OutputTokens != 7 {
+		t.Errorf("unexpected haiku usage %+v", u)
+	}
+}
@@ -0,0 +1,66 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aquila/clancy/model"
+)
+
+// renderAgentRun renders a subagent run nested under the Task call that
+// spawned it, or on its own when tool is nil. Collapsed it's a one-line
+// summary; expanded it lists the run's events behind a gutter.
+func renderAgentRun(agent *model.Agent, tool *model.ToolUse, o renderOpts) string {
+	summary := []string{fmt.Sprintf("%d events", len(agent.Events))}
+	if u := agent.Usage(); u != (model.Usage{}) {
+		summary = append(summary, fmt.Sprintf("↑%s ↓%s", formatTokens(u.ContextTokens()), formatTokens(u.OutputTokens)))
+	}
+	duration := agent.Duration()
+	if tool != nil && duration == 0 {
+		duration = tool.Latency()
+	}
+	if duration > 0 {
+		summary = append(summary, formatLatency(duration))
+	}
+	if tool != nil && tool.Pending() {
+		summary = append(summary, "running")
+	}
+	marker := "▸"
+	if o.expanded {
+		marker = "▾"
+	}
+	header := "  " + agentStyle.Render(marker+" subagent "+shortAgentID(agent.ID)) + " " + usageStyle.Render(strings.Join(summary, " · "))
+	if !o.expanded || len(agent.Events) == 0 {
+		return header
+	}
+
+	// Nested events are shown collapsed; the run as a whole is what expands
+	nested := renderOpts{width: o.width - 4, highlight: o.highlight, hideToolOutput: o.hideToolOutput, graphics: o.graphics, cached: o.cached}
+	lines := []string{header}
+	for _, event := range agent.Events {
+		block := strings.TrimRight(renderEvent(event, nested), " \n")
+		if block == "" {
+			continue
+		}
+		for _, line := range strings.Split(block, "\n") {
+			lines = append(lines, "  "+agentStyle.Render("│")+line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// renderAgent renders a subagent run that no known Task call spawned
+func renderAgent(event *model.DisplayEvent, o renderOpts) string {
+	if event.Agent == nil {
+		return ""
+	}
+	return eventStyle.Width(o.width).Render(renderAgentRun(event.Agent, nil, o))
+}
+
+// shortAgentID abbreviates an agent ID or uuid for display
+func shortAgentID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
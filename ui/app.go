@@ -2,50 +2,136 @@ package ui
 
 import (
 	"fmt"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/aquila/clancy/model"
-	"github.com/aquila/clancy/parser"
+	"github.com/aquila/clancy/pricing"
 	"github.com/aquila/clancy/watcher"
 	tea "github.com/charmbracelet/bubbletea"
 )
 
-// Model is the main bubbletea model
+// Model is the main bubbletea model. The embedded tab is the session on
+// screen; the others wait in tabs.
 type Model struct {
-	filename   string
-	watcher    *watcher.Watcher
-	parser     *parser.Parser
-	events     []*model.DisplayEvent
+	tab
+	tabs   []tab // every open session; tabs[active] is stale while it's on screen
+	active int
+
+	dirWatcher *watcher.DirWatcher // follows new sessions in the project directory
 	width      int
 	height     int
-	offset     int // scroll offset
-	followMode bool
-	err        error
+	expandAll  bool
+
+	filter filter
+	picker picker
+	stats  stats
+	diag   diagPanel
+
+	notice      string    // transient message shown in the status bar
+	noticeUntil time.Time // when the notice disappears
+
+	highlight   bool          // syntax highlight code
+	prices      pricing.Table // for estimating cost from token usage
+	idleTimeout time.Duration // for watchers of sessions opened from the picker
+
+	graphics graphics // inline image protocol of the terminal
+	saveDir  string   // where w saves images; "" is the current directory
+}
+
+// Options configures a new UI model
+type Options struct {
+	Hide []string // event kinds to hide
+	Only []string // if set, only these event kinds are shown
+	Pick bool     // start in the session picker
+
+	NoHighlight bool          // disable syntax highlighting, e.g. on slow terminals
+	Prices      pricing.Table // model prices; the built-in table if nil
+
+	IdleTimeout time.Duration // mark sessions finished after this long without new lines
+
+	// FollowDir is a project directory to watch for new session files. When
+	// one appears, e.g. after /clear, the UI switches to it.
+	FollowDir string
 }
 
-// lineMsg is a message containing a new line from the watcher
-type lineMsg []byte
+// sessionFileMsg reports a new session file in the followed directory
+type sessionFileMsg struct {
+	path string
+}
 
-// errMsg is a message containing an error
-type errMsg error
+// tickMsg refreshes time-based state such as "idle for 2m"
+type tickMsg struct{}
+
+// lineMsg is a message containing a new line from a watcher
+type lineMsg struct {
+	watcher *watcher.Watcher
+	line    watcher.Line
+}
+
+// errMsg is a message containing an error from a watcher
+type errMsg struct {
+	watcher *watcher.Watcher
+	err     error
+}
 
 // New creates a new UI model
-func New(filename string, w *watcher.Watcher) Model {
+func New(filename string, w *watcher.Watcher, opts Options) Model {
+	prices := opts.Prices
+	if prices == nil {
+		prices = pricing.Default()
+	}
+	var dw *watcher.DirWatcher
+	if opts.FollowDir != "" {
+		dw = watcher.NewDir(opts.FollowDir)
+		if err := dw.Start(); err != nil {
+			dw = nil // keep working on the current session only
+		}
+	}
+	first := newTab(filename, w)
+	first.followsDir = dw != nil
 	return Model{
-		filename:   filename,
-		watcher:    w,
-		parser:     parser.New(),
-		events:     make([]*model.DisplayEvent, 0),
-		followMode: true,
+		tab:        first,
+		tabs:       []tab{first},
+		dirWatcher: dw,
+		filter:     newFilter(opts.Hide, opts.Only),
+		picker:     picker{open: opts.Pick},
+		highlight:  !opts.NoHighlight,
+		prices:     prices,
+
+		idleTimeout: opts.IdleTimeout,
+
+		graphics: detectGraphics(),
 	}
 }
 
 // Init initializes the model
 func (m Model) Init() tea.Cmd {
-	return tea.Batch(
-		waitForLine(m.watcher),
-		waitForError(m.watcher),
-	)
+	var cmds []tea.Cmd
+	for i, t := range m.tabs {
+		if i == m.active {
+			t = m.tab
+		}
+		if t.watcher != nil {
+			cmds = append(cmds, waitForLine(t.watcher), waitForError(t.watcher))
+		}
+	}
+	if m.dirWatcher != nil {
+		cmds = append(cmds, waitForSessionFile(m.dirWatcher))
+	}
+	if m.picker.open {
+		cmds = append(cmds, loadSessions(false))
+	}
+	cmds = append(cmds, tick())
+	return tea.Batch(cmds...)
+}
+
+// tick schedules the next status refresh
+func tick() tea.Cmd {
+	return tea.Tick(time.Second, func(time.Time) tea.Msg {
+		return tickMsg{}
+	})
 }
 
 // waitForLine waits for the next line from the watcher
@@ -55,7 +141,18 @@ func waitForLine(w *watcher.Watcher) tea.Cmd {
 		if !ok {
 			return nil
 		}
-		return lineMsg(line)
+		return lineMsg{watcher: w, line: line}
+	}
+}
+
+// waitForSessionFile waits for a new session file in the followed directory
+func waitForSessionFile(d *watcher.DirWatcher) tea.Cmd {
+	return func() tea.Msg {
+		path, ok := <-d.Files()
+		if !ok {
+			return nil
+		}
+		return sessionFileMsg{path: path}
 	}
 }
 
@@ -66,7 +163,7 @@ func waitForError(w *watcher.Watcher) tea.Cmd {
 		if !ok {
 			return nil
 		}
-		return errMsg(err)
+		return errMsg{watcher: w, err: err}
 	}
 }
 
@@ -74,35 +171,154 @@ func waitForError(w *watcher.Watcher) tea.Cmd {
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		if m.picker.open {
+			return m.updatePicker(msg)
+		}
+		if m.stats.open {
+			return m.updateStats(msg)
+		}
+		if m.diag.open {
+			return m.updateDiagnostics(msg)
+		}
+		if m.search.typing {
+			return m.updateSearchInput(msg), nil
+		}
+
 		switch msg.String() {
 		case "q", "ctrl+c":
-			m.watcher.Stop()
-			return m, tea.Quit
+			return m.quit()
+
+		case "p":
+			return m.openPicker(false)
+
+		case "tab":
+			m.switchTab(m.active + 1)
+
+		case "shift+tab":
+			m.switchTab(m.active - 1)
+
+		case "1", "2", "3", "4", "5", "6", "7", "8", "9":
+			if i := int(msg.String()[0] - '1'); i < len(m.tabs) {
+				m.switchTab(i)
+			}
+
+		case "ctrl+w":
+			m.closeTab()
+
+		case "s":
+			m.stats = stats{open: true, offset: m.maxStatsOffset()}
+
+		case "d":
+			m.diag = diagPanel{open: true, offset: m.maxDiagOffset()}
+
+		case "w":
+			m.saveAttachments()
+
+		case "/":
+			m.search = search{typing: true, current: -1}
+
+		case "n":
+			m.jumpToMatch(1, true)
+
+		case "N":
+			m.jumpToMatch(-1, true)
+
+		case "esc":
+			m.search = search{current: -1}
 
 		case "up", "k":
 			if m.offset > 0 {
 				m.offset--
 				m.followMode = false
 			}
+			m.syncCursorToView()
 
 		case "down", "j":
 			maxOffset := m.maxOffset()
 			if m.offset < maxOffset {
 				m.offset++
 			}
+			m.syncCursorToView()
+
+		case "K", "shift+up":
+			m.moveCursor(-1)
+			m.followMode = false
+			m.scrollToCursor()
+
+		case "J", "shift+down":
+			m.moveCursor(1)
+			m.scrollToCursor()
+
+		case "enter":
+			if m.cursor < len(m.events) {
+				event := m.events[m.cursor]
+				m.expanded[event] = !m.isExpanded(event)
+				m.scrollToCursor()
+			}
+
+		case "+", "=":
+			m.expandAll = true
+			m.expanded = make(map[*model.DisplayEvent]bool)
+			m.scrollToCursor()
+
+		case "-":
+			m.expandAll = false
+			m.expanded = make(map[*model.DisplayEvent]bool)
+			m.scrollToCursor()
+
+		case "t":
+			m.filter.toggle("thinking")
+			m.applyFilter()
+
+		case "o":
+			m.filter.toggle("tool_result")
+			m.applyFilter()
+
+		case "x":
+			m.filter.toggleOnly("error")
+			m.applyFilter()
+
+		case "c":
+			m.jumpToCompaction(1)
+
+		case "C":
+			m.jumpToCompaction(-1)
+
+		case "b":
+			m.switchBranch(1)
+
+		case "B":
+			m.switchBranch(-1)
+
+		case "e":
+			m.jumpToError(1)
+
+		case "E":
+			m.jumpToError(-1)
+
+		case "F":
+			m.filter = newFilter(nil, nil)
+			m.applyFilter()
+
+		case "H":
+			m.highlight = !m.highlight
+			m.scrollToCursor()
 
 		case "g", "home":
 			m.offset = 0
+			m.cursor = m.firstEvent()
 			m.followMode = false
 
 		case "G", "end":
 			m.offset = m.maxOffset()
+			m.cursor = m.lastEvent()
 			m.followMode = true
 
 		case "f":
 			m.followMode = !m.followMode
 			if m.followMode {
 				m.offset = m.maxOffset()
+				m.cursor = m.lastEvent()
 			}
 
 		case "pgup":
@@ -111,6 +327,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.offset = 0
 			}
 			m.followMode = false
+			m.syncCursorToView()
 
 		case "pgdown":
 			m.offset += m.viewportHeight()
@@ -118,25 +335,82 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if m.offset > maxOffset {
 				m.offset = maxOffset
 			}
+			m.syncCursorToView()
 		}
 
+	case tickMsg:
+		if m.notice != "" && time.Now().After(m.noticeUntil) {
+			m.notice = ""
+		}
+		return m, tick()
+
+	case sessionFileMsg:
+		m, cmd := m.followSession(msg.path)
+		return m, tea.Batch(cmd, waitForSessionFile(m.dirWatcher))
+
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
 
+	case sessionsMsg:
+		if msg.allProjects != m.picker.allProjects {
+			return m, nil // superseded by a later toggle
+		}
+		m.picker.loading = false
+		m.picker.sessions = msg.sessions
+		m.picker.err = msg.err
+		m.picker.cursor = 0
+		m.picker.offset = 0
+		return m, nil
+
 	case lineMsg:
-		events, err := m.parser.ParseLine(msg)
-		if err == nil && len(events) > 0 {
-			m.events = append(m.events, events...)
-			if m.followMode {
-				m.offset = m.maxOffset()
+		i := m.tabIndex(msg.watcher)
+		if i < 0 {
+			return m, nil // a stale line from a closed session
+		}
+		if msg.line.Reset {
+			// Only the session file starts the tab over; a rewritten subagent
+			// transcript is rare enough to leave as is
+			if i != m.active && m.tabs[i].watcher == msg.watcher {
+				m.tabs[i].restart()
+			} else if i == m.active && m.watcher == msg.watcher {
+				m.restart()
+				m.offset = 0
 			}
+			return m, waitForLine(msg.watcher)
+		}
+		if i != m.active {
+			t := &m.tabs[i]
+			t.unread += t.addLine(msg.watcher, msg.line)
+			return m, tea.Batch(waitForLine(msg.watcher), t.watchAgentFiles(m.idleTimeout))
+		}
+		m.addLine(msg.watcher, msg.line)
+		if m.search.query != "" {
+			m.updateMatches()
 		}
-		return m, waitForLine(m.watcher)
+		// A tool result may have grown an existing event without adding one
+		if m.followMode {
+			m.offset = m.maxOffset()
+			m.cursor = m.lastEvent()
+		}
+		return m, tea.Batch(waitForLine(msg.watcher), m.watchAgentFiles(m.idleTimeout))
 
 	case errMsg:
-		m.err = msg
-		return m, waitForError(m.watcher)
+		i := m.tabIndex(msg.watcher)
+		if i < 0 {
+			return m, nil
+		}
+		d := model.Diagnostic{
+			Position: model.Position{File: msg.watcher.Path()},
+			Kind:     "watcher",
+			Message:  msg.err.Error(),
+		}
+		if i != m.active {
+			m.tabs[i].diagnostics = append(m.tabs[i].diagnostics, d)
+		} else {
+			m.diagnostics = append(m.diagnostics, d)
+		}
+		return m, waitForError(msg.watcher)
 	}
 
 	return m, nil
@@ -147,17 +421,29 @@ func (m Model) View() string {
 	if m.width == 0 {
 		return "Loading..."
 	}
+	if m.picker.open {
+		return m.viewPicker()
+	}
+	if m.stats.open {
+		return m.viewStats()
+	}
+	if m.diag.open {
+		return m.viewDiagnostics()
+	}
 
 	var b strings.Builder
 
 	// Status bar
-	b.WriteString(renderStatusBar(m.filename, len(m.events), m.width))
+	b.WriteString(renderStatusBar(m.filename, len(m.events), m.width, m.notice, m.watcherStatus(), m.branchStatus(), m.filter.status(), m.search.status(), m.errorStatus(), m.diagnosticStatus(), m.tokenStatus(), m.costStatus()))
 	b.WriteString("\n")
+	if len(m.tabs) > 1 {
+		b.WriteString(m.renderTabBar())
+		b.WriteString("\n")
+	}
 
 	// Viewport content
 	viewportHeight := m.viewportHeight()
-	content := m.renderEvents()
-	lines := strings.Split(content, "\n")
+	lines, _ := m.layout()
 
 	// Apply scroll offset
 	start := m.offset
@@ -170,6 +456,12 @@ func (m Model) View() string {
 	}
 
 	visibleLines := lines[start:end]
+	clipSixel(visibleLines)
+	if m.search.query != "" {
+		for i, line := range visibleLines {
+			visibleLines[i] = highlightMatches(line, m.search.query)
+		}
+	}
 
 	// Pad to fill viewport
 	for len(visibleLines) < viewportHeight {
@@ -179,31 +471,311 @@ func (m Model) View() string {
 	b.WriteString(strings.Join(visibleLines, "\n"))
 	b.WriteString("\n")
 
-	// Help bar
-	b.WriteString(renderHelpBar(m.followMode, m.width))
+	// Help bar, replaced by the prompt while typing a search
+	if m.search.typing {
+		b.WriteString(renderSearchPrompt(m.search.query, m.width))
+	} else {
+		b.WriteString(renderHelpBar(m.followMode, m.width))
+	}
 
 	return b.String()
 }
 
-// renderEvents renders all events
-func (m Model) renderEvents() string {
-	var parts []string
+// openSession switches to another transcript, resetting all session state
+func (m Model) openSession(path string) (Model, tea.Cmd) {
+	w := watcher.New(path)
+	w.IdleTimeout = m.idleTimeout
+	if err := w.Start(); err != nil {
+		m.watcherFailed(path, err)
+		return m, nil
+	}
+	m.stop()
+	follows := m.followsDir
+	m.tab = newTab(path, w)
+	m.followsDir = follows
+	m.stats = stats{}
+	m.diag = diagPanel{}
+	return m, tea.Batch(waitForLine(w), waitForError(w))
+}
+
+// updateSearchInput handles keys while the search prompt is open. Matches are
+// recomputed and the first hit selected on every keystroke.
+func (m Model) updateSearchInput(msg tea.KeyMsg) Model {
+	switch msg.Type {
+	case tea.KeyEnter:
+		m.search.typing = false
+		if m.search.current >= 0 {
+			m.expanded[m.events[m.cursor]] = true
+			m.scrollToCursor()
+		}
+		return m
+	case tea.KeyEsc, tea.KeyCtrlC:
+		m.search = search{current: -1}
+		return m
+	case tea.KeyBackspace:
+		if len(m.search.query) > 0 {
+			runes := []rune(m.search.query)
+			m.search.query = string(runes[:len(runes)-1])
+		}
+	case tea.KeyRunes, tea.KeySpace:
+		m.search.query += string(msg.Runes)
+	default:
+		return m
+	}
+
+	m.search.current = -1
+	m.updateMatches()
+	if len(m.search.matches) > 0 {
+		// Search from just before the cursor so the current event can match
+		m.cursor--
+		m.jumpToMatch(1, false)
+	}
+	return m
+}
+
+// layout renders all events into lines and records the first line of each
+// event, or -1 for events that render nothing
+func (m Model) layout() ([]string, []int) {
+	var lines []string
+	starts := make([]int, len(m.events))
+	for i, event := range m.events {
+		starts[i] = -1
+		if !m.filter.visible(event) {
+			continue
+		}
+		rendered := m.rendered.render(event, renderOpts{
+			width:          m.width,
+			expanded:       m.isExpanded(event),
+			hideToolOutput: !m.filter.showsOutput(event),
+			highlight:      m.highlight,
+			graphics:       m.graphics,
+		})
+		if rendered == nil {
+			continue
+		}
+		starts[i] = len(lines)
+		lines = append(lines, rendered...)
+		block := lines[starts[i]:] // a copy, safe to modify
+		images := m.rendered[event].images
+		switch m.graphics {
+		case sixelGraphics:
+			for j, line := range block {
+				block[j] = expandSixel(line, images)
+			}
+		case kittyGraphics:
+			for j, line := range block {
+				block[j] = expandKitty(line, images)
+			}
+		}
+		if i == m.cursor {
+			markCursor(block)
+		}
+	}
+	if len(lines) == 0 {
+		content := fmt.Sprintf("\n  Waiting for events from %s...\n", m.filename)
+		return strings.Split(content, "\n"), starts
+	}
+	return lines, starts
+}
+
+// markCursor replaces the left padding of a selected event with a gutter bar,
+// leaving the trailing margin line blank
+func markCursor(block []string) {
+	for i := 0; i < len(block)-1; i++ {
+		if strings.HasPrefix(block[i], " ") {
+			block[i] = cursorStyle.Render("▎") + block[i][1:]
+		}
+	}
+}
+
+// isExpanded reports whether an event shows its full content
+func (m Model) isExpanded(event *model.DisplayEvent) bool {
+	if v, ok := m.expanded[event]; ok {
+		return v
+	}
+	return m.expandAll
+}
+
+// firstEvent returns the index of the oldest visible event
+func (m Model) firstEvent() int {
+	for i, event := range m.events {
+		if m.filter.visible(event) {
+			return i
+		}
+	}
+	return 0
+}
+
+// lastEvent returns the index of the newest visible event
+func (m Model) lastEvent() int {
+	for i := len(m.events) - 1; i >= 0; i-- {
+		if m.filter.visible(m.events[i]) {
+			return i
+		}
+	}
+	return 0
+}
+
+// moveCursor selects the next (dir > 0) or previous (dir < 0) visible event
+func (m *Model) moveCursor(dir int) {
+	for i := m.cursor + dir; i >= 0 && i < len(m.events); i += dir {
+		if m.filter.visible(m.events[i]) {
+			m.cursor = i
+			return
+		}
+	}
+}
+
+// jumpToError selects the next (dir > 0) or previous (dir < 0) failed event
+func (m *Model) jumpToError(dir int) {
+	for i := m.cursor + dir; i >= 0 && i < len(m.events); i += dir {
+		if m.events[i].Failed() && m.filter.visible(m.events[i]) {
+			m.cursor = i
+			m.followMode = false
+			m.scrollToCursor()
+			return
+		}
+	}
+}
+
+// jumpToCompaction selects the next or previous compaction point
+func (m *Model) jumpToCompaction(dir int) {
+	for i := m.cursor + dir; i >= 0 && i < len(m.events); i += dir {
+		if m.events[i].Type == "compact" && m.filter.visible(m.events[i]) {
+			m.cursor = i
+			m.followMode = false
+			m.scrollToCursor()
+			return
+		}
+	}
+}
+
+// quit stops the watchers and exits
+func (m Model) quit() (Model, tea.Cmd) {
+	m.saveTab()
+	for _, t := range m.tabs {
+		t.stop()
+	}
+	if m.dirWatcher != nil {
+		m.dirWatcher.Stop()
+	}
+	return m, tea.Quit
+}
+
+// showNotice displays a message in the status bar for a few seconds
+func (m *Model) showNotice(notice string) {
+	m.notice = notice
+	m.noticeUntil = time.Now().Add(5 * time.Second)
+}
+
+// shortSessionID abbreviates a session file's UUID for notices, e.g. "7f3a…"
+func shortSessionID(path string) string {
+	id := strings.TrimSuffix(filepath.Base(path), ".jsonl")
+	if len(id) > 4 {
+		id = id[:4] + "…"
+	}
+	return id
+}
+
+// watcherStatus shows whether the session is running, idle or finished
+func (m Model) watcherStatus() string {
+	if m.watcher == nil {
+		return ""
+	}
+	return m.watcher.Status().String()
+}
+
+// errorStatus summarizes failed events for the status bar
+func (m Model) errorStatus() string {
+	count := 0
 	for _, event := range m.events {
-		rendered := renderEvent(event, m.width)
-		if rendered != "" {
-			parts = append(parts, rendered)
+		if event.Failed() {
+			count++
+		}
+	}
+	switch count {
+	case 0:
+		return ""
+	case 1:
+		return "✗ 1 error"
+	}
+	return fmt.Sprintf("✗ %d errors", count)
+}
+
+// applyFilter refreshes everything derived from the filter after it changes
+func (m *Model) applyFilter() {
+	if m.search.query != "" {
+		m.updateMatches()
+	}
+	if m.cursor < len(m.events) && !m.filter.visible(m.events[m.cursor]) {
+		m.moveCursor(-1)
+		if !m.filter.visible(m.events[m.cursor]) {
+			m.moveCursor(1)
+		}
+	}
+	if m.followMode {
+		m.offset = m.maxOffset()
+		m.cursor = m.lastEvent()
+		return
+	}
+	m.scrollToCursor()
+}
+
+// scrollToCursor adjusts the offset so the selected event is on screen
+func (m *Model) scrollToCursor() {
+	lines, starts := m.layout()
+	if m.cursor >= len(starts) || starts[m.cursor] < 0 {
+		return
+	}
+	top := starts[m.cursor]
+	bottom := len(lines)
+	for _, s := range starts[m.cursor+1:] {
+		if s >= 0 {
+			bottom = s
+			break
 		}
 	}
-	if len(parts) == 0 {
-		return fmt.Sprintf("\n  Waiting for events from %s...\n", m.filename)
+
+	viewportHeight := m.viewportHeight()
+	if top < m.offset {
+		m.offset = top
+	} else if bottom > m.offset+viewportHeight {
+		m.offset = bottom - viewportHeight
+		if m.offset > top {
+			m.offset = top
+		}
+	}
+	if maxOffset := m.maxOffset(); m.offset > maxOffset {
+		m.offset = maxOffset
+	}
+}
+
+// syncCursorToView moves the cursor onto the top visible event when line
+// scrolling leaves it off screen
+func (m *Model) syncCursorToView() {
+	_, starts := m.layout()
+	if m.cursor < len(starts) && starts[m.cursor] >= m.offset && starts[m.cursor] < m.offset+m.viewportHeight() {
+		return
+	}
+	// Select the event that contains the top visible line
+	for i, s := range starts {
+		if s < 0 {
+			continue
+		}
+		if s > m.offset {
+			break
+		}
+		m.cursor = i
 	}
-	return strings.Join(parts, "\n")
 }
 
 // viewportHeight returns the height available for events
 func (m Model) viewportHeight() int {
-	// Total height minus status bar (1) and help bar (1)
+	// Total height minus status bar (1), help bar (1) and the tab bar
 	h := m.height - 2
+	if len(m.tabs) > 1 {
+		h--
+	}
 	if h < 1 {
 		h = 1
 	}
@@ -212,8 +784,7 @@ func (m Model) viewportHeight() int {
 
 // maxOffset returns the maximum scroll offset
 func (m Model) maxOffset() int {
-	content := m.renderEvents()
-	lines := strings.Split(content, "\n")
+	lines, _ := m.layout()
 	max := len(lines) - m.viewportHeight()
 	if max < 0 {
 		return 0
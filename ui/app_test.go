@@ -0,0 +1,726 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/aquila/clancy/model"
+	"github.com/aquila/clancy/pricing"
+	"github.com/aquila/clancy/watcher"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// newTestModel returns a sized model preloaded with events
+func newTestModel(events ...*model.DisplayEvent) Model {
+	m := New("test.jsonl", nil, Options{})
+	m.width = 80
+	m.height = 20
+	m.events = events
+	return m
+}
+
+func press(m Model, key string) Model {
+	var msg tea.KeyMsg
+	switch key {
+	case "enter":
+		msg = tea.KeyMsg{Type: tea.KeyEnter}
+	case "tab":
+		msg = tea.KeyMsg{Type: tea.KeyTab}
+	case "ctrl+w":
+		msg = tea.KeyMsg{Type: tea.KeyCtrlW}
+	default:
+		msg = tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)}
+	}
+	updated, _ := m.Update(msg)
+	return updated.(Model)
+}
+
+func TestCursorMovesBetweenEvents(t *testing.T) {
+	m := newTestModel(
+		&model.DisplayEvent{Type: "user", Text: "first"},
+		&model.DisplayEvent{Type: "assistant", Text: "second"},
+		&model.DisplayEvent{Type: "assistant", Text: "third"},
+	)
+
+	m = press(m, "J")
+	m = press(m, "J")
+	if m.cursor != 2 {
+		t.Errorf("expected cursor on last event, got %d", m.cursor)
+	}
+	m = press(m, "J")
+	if m.cursor != 2 {
+		t.Errorf("expected cursor to stop at last event, got %d", m.cursor)
+	}
+	m = press(m, "K")
+	if m.cursor != 1 {
+		t.Errorf("expected cursor to move back, got %d", m.cursor)
+	}
+}
+
+func TestEnterTogglesSelectedEvent(t *testing.T) {
+	var lines []string
+	for i := 0; i < 10; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	long := &model.DisplayEvent{Type: "assistant", Text: strings.Join(lines, "\n")}
+	m := newTestModel(long)
+
+	if strings.Contains(m.View(), "line 9") {
+		t.Fatal("expected event to start collapsed")
+	}
+	m = press(m, "enter")
+	if !strings.Contains(m.View(), "line 9") {
+		t.Error("expected enter to expand the selected event")
+	}
+	m = press(m, "enter")
+	if strings.Contains(m.View(), "line 9") {
+		t.Error("expected second enter to collapse the event")
+	}
+
+	m = press(m, "+")
+	if !m.isExpanded(long) {
+		t.Error("expected + to expand every event")
+	}
+	m = press(m, "-")
+	if m.isExpanded(long) {
+		t.Error("expected - to collapse every event")
+	}
+}
+
+func typeKeys(m Model, keys string) Model {
+	for _, r := range keys {
+		updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+		m = updated.(Model)
+	}
+	return m
+}
+
+func TestSearchJumpsBetweenMatches(t *testing.T) {
+	m := newTestModel(
+		&model.DisplayEvent{Type: "user", Text: "run the tests"},
+		&model.DisplayEvent{Type: "assistant", ToolUse: &model.ToolUse{
+			Name:   "Bash",
+			Input:  `{"command":"go test ./..."}`,
+			Result: &model.ToolResult{Content: "FAIL parser"},
+		}},
+		&model.DisplayEvent{Type: "assistant", Text: "nothing here"},
+		&model.DisplayEvent{Type: "assistant", Text: "the parser FAIL is fixed"},
+	)
+
+	m = press(m, "/")
+	m = typeKeys(m, "fail")
+	m = press(m, "enter")
+
+	if got := m.search.status(); got != "/fail 1/2" {
+		t.Errorf("expected status /fail 1/2, got %q", got)
+	}
+	if m.cursor != 1 {
+		t.Errorf("expected cursor on tool result match, got %d", m.cursor)
+	}
+
+	m = press(m, "n")
+	if m.cursor != 3 || m.search.status() != "/fail 2/2" {
+		t.Errorf("expected second match selected, got cursor %d status %q", m.cursor, m.search.status())
+	}
+	m = press(m, "n")
+	if m.cursor != 1 {
+		t.Errorf("expected n to wrap to first match, got %d", m.cursor)
+	}
+	m = press(m, "N")
+	if m.cursor != 3 {
+		t.Errorf("expected N to wrap to last match, got %d", m.cursor)
+	}
+
+	// New events streaming in are searched too
+	updated, _ := m.Update(lineMsg{line: watcher.Line{Data: []byte(`{"type":"assistant","message":{"role":"assistant","content":[{"type":"text","text":"another fail"}]}}`)}})
+	m = updated.(Model)
+	if len(m.search.matches) != 3 {
+		t.Errorf("expected streamed event to match, got %d matches", len(m.search.matches))
+	}
+}
+
+func TestHighlightMatches(t *testing.T) {
+	styled := textStyle.Render("go test ./...") + " " + errorStyle.Render("FAILED")
+	if got := highlightMatches(styled, "fail"); !strings.Contains(got, "FAILED") {
+		t.Errorf("expected match text preserved, got %q", got)
+	}
+	if got := highlightMatches("no hits", "fail"); got != "no hits" {
+		t.Errorf("expected untouched line, got %q", got)
+	}
+
+	// Lowercasing "Ⱥ" or the Kelvin sign changes their byte length
+	for _, line := range []string{"ȺȺȺȺ foo", "İstanbul foo", "5 \u212a foo"} {
+		if got := ansi.Strip(highlightMatches(line, "foo")); got != line {
+			t.Errorf("expected %q kept intact, got %q", line, got)
+		}
+	}
+
+	// Images are left alone even when their encoding happens to match
+	for _, line := range []string{
+		"\x1b_Gf=100,a=T,i=1,m=0;Zm9vYmFy\x1b\\",
+		"  \x1b[38;2;0;0;1m\U0010EEEE\u0305\u0305\U0010EEEE\x1b[39m foo",
+		"\x1b7\x1b[8A\r\x1b[2C\x1bPq\"1;1;2;2#0foo-\x1b\\\x1b8",
+	} {
+		if got := highlightMatches(line, "foo"); got != line {
+			t.Errorf("expected image line %q untouched, got %q", line, got)
+		}
+	}
+	if start, end := findMatch("x ȺȺ y", "ⱥⱥ", true); start != 2 || end != 6 {
+		t.Errorf("expected the case-folded match at bytes 2-6, got %d-%d", start, end)
+	}
+}
+
+func TestFilterHidesEventKinds(t *testing.T) {
+	thinking := &model.DisplayEvent{Type: "thinking", Text: "pondering deeply"}
+	tool := &model.DisplayEvent{Type: "assistant", ToolUse: &model.ToolUse{
+		Name:   "Bash",
+		Input:  `{"command":"ls"}`,
+		Result: &model.ToolResult{Content: "main.go"},
+	}}
+	m := newTestModel(
+		&model.DisplayEvent{Type: "user", Text: "hello"},
+		thinking,
+		tool,
+	)
+
+	m = press(m, "t")
+	view := m.View()
+	if strings.Contains(view, "pondering") {
+		t.Error("expected thinking to be hidden")
+	}
+	if !strings.Contains(view, "main.go") {
+		t.Error("expected tool output to stay visible")
+	}
+
+	m = press(m, "o")
+	if strings.Contains(m.View(), "main.go") {
+		t.Error("expected tool output to be hidden")
+	}
+	if !strings.Contains(m.View(), "Bash") {
+		t.Error("expected tool call to stay visible")
+	}
+
+	m = press(m, "F")
+	if !strings.Contains(m.View(), "pondering") {
+		t.Error("expected F to clear filters")
+	}
+}
+
+func TestFilterOnlyAndCursor(t *testing.T) {
+	m := New("test.jsonl", nil, Options{Only: []string{"tool_use"}})
+	m.width, m.height = 80, 20
+	m.events = []*model.DisplayEvent{
+		{Type: "assistant", ToolUse: &model.ToolUse{Name: "Read", Input: `{}`}},
+		{Type: "assistant", Text: "middle text"},
+		{Type: "assistant", ToolUse: &model.ToolUse{Name: "Grep", Input: `{}`}},
+	}
+
+	if strings.Contains(m.View(), "middle text") {
+		t.Error("expected text to be filtered out")
+	}
+	m = press(m, "J")
+	if m.cursor != 2 {
+		t.Errorf("expected cursor to skip hidden event, got %d", m.cursor)
+	}
+}
+
+func TestFilterErrorKind(t *testing.T) {
+	if err := ValidateKinds([]string{"error"}); err != nil {
+		t.Fatal(err)
+	}
+	events := []*model.DisplayEvent{
+		{Type: "assistant", ToolUse: &model.ToolUse{Name: "Bash", Input: `{}`, Result: &model.ToolResult{Content: "ok"}}},
+		{Type: "assistant", ToolUse: &model.ToolUse{Name: "Bash", Input: `{}`, Result: &model.ToolResult{Content: "exit 1", IsError: true}}},
+		{Type: "tool_result", ToolResult: &model.ToolResult{Content: "denied", IsError: true}},
+		{Type: "result", Subtype: "error_max_turns", IsError: true},
+		{Type: "assistant", Text: "done"},
+	}
+	visible := func(f filter) []bool {
+		var got []bool
+		for _, event := range events {
+			got = append(got, f.visible(event))
+		}
+		return got
+	}
+
+	if got, want := visible(newFilter([]string{"error"}, nil)), []bool{true, false, false, false, true}; !slices.Equal(got, want) {
+		t.Errorf("--hide error: expected %v, got %v", want, got)
+	}
+	if got, want := visible(newFilter(nil, []string{"error"})), []bool{false, true, true, true, false}; !slices.Equal(got, want) {
+		t.Errorf("--only error: expected %v, got %v", want, got)
+	}
+}
+
+func TestErrorsJumpCountAndFilter(t *testing.T) {
+	failed := &model.DisplayEvent{Type: "assistant", ToolUse: &model.ToolUse{
+		Name:   "Bash",
+		Input:  `{"command":"make"}`,
+		Result: &model.ToolResult{Content: "make: *** [all] Error 2", IsError: true},
+	}}
+	m := newTestModel(
+		&model.DisplayEvent{Type: "user", Text: "build it"},
+		failed,
+		&model.DisplayEvent{Type: "assistant", Text: "retrying"},
+		&model.DisplayEvent{Type: "result", Subtype: "error_max_turns", IsError: true, Text: "error_max_turns | 50 turns"},
+	)
+
+	if got := m.errorStatus(); got != "✗ 2 errors" {
+		t.Errorf("unexpected error status %q", got)
+	}
+	if !strings.Contains(m.View(), "✗ 2 errors") {
+		t.Error("expected error count in status bar")
+	}
+
+	m = press(m, "e")
+	if m.cursor != 1 {
+		t.Errorf("expected first error selected, got %d", m.cursor)
+	}
+	m = press(m, "e")
+	if m.cursor != 3 {
+		t.Errorf("expected second error selected, got %d", m.cursor)
+	}
+	m = press(m, "E")
+	if m.cursor != 1 {
+		t.Errorf("expected previous error selected, got %d", m.cursor)
+	}
+
+	m = press(m, "x")
+	view := m.View()
+	if strings.Contains(view, "retrying") || strings.Contains(view, "build it") {
+		t.Error("expected only errors to be shown")
+	}
+	if !strings.Contains(view, "Error 2") {
+		t.Error("expected failed tool output to stay visible")
+	}
+}
+
+func TestTokenStatusAndStatsPanel(t *testing.T) {
+	m := newTestModel()
+	for _, line := range []string{
+		`{"type":"assistant","message":{"id":"msg_1","model":"claude-opus-4-1","role":"assistant","content":[{"type":"text","text":"one"}],"usage":{"input_tokens":100,"cache_read_input_tokens":49900,"output_tokens":500,"service_tier":"standard"}}}`,
+		`{"type":"assistant","message":{"id":"msg_2","model":"claude-opus-4-1","role":"assistant","content":[{"type":"text","text":"two"}],"usage":{"input_tokens":200,"cache_creation_input_tokens":800,"cache_read_input_tokens":99000,"output_tokens":1500}}}`,
+	} {
+		events, err := m.parser.ParseLine([]byte(line))
+		if err != nil {
+			t.Fatal(err)
+		}
+		m.events = append(m.events, events...)
+	}
+
+	// 150k prompt tokens in total; the latest message fills half the window
+	if got := m.tokenStatus(); got != "↑150.0k ↓2.0k ctx 50%" {
+		t.Errorf("unexpected token status %q", got)
+	}
+	if !strings.Contains(m.View(), "ctx 50%") {
+		t.Error("expected context fill in status bar")
+	}
+
+	m.width = 100
+	m = press(m, "s")
+	view := m.View()
+	for _, want := range []string{"opus-4-1", "49.9k", "99.0k", "standard", "100.0k 50%", "total"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("expected stats panel to contain %q:\n%s", want, view)
+		}
+	}
+
+	m = press(m, "s")
+	if m.stats.open {
+		t.Error("expected s to close the stats panel")
+	}
+}
+
+func TestCostEstimateAndModelSubtotals(t *testing.T) {
+	m := newTestModel()
+	m.width = 100
+	m.prices = pricing.Table{
+		"claude-opus":   {Input: 10, Output: 50},
+		"claude-sonnet": {Input: 2, Output: 10, CacheWrite: 2.5, CacheRead: 0.2},
+	}
+	for _, line := range []string{
+		`{"type":"assistant","message":{"id":"msg_1","model":"claude-opus-x","role":"assistant","content":[{"type":"text","text":"plan"}],"usage":{"input_tokens":100000,"output_tokens":10000}}}`,
+		`{"type":"assistant","message":{"id":"msg_2","model":"claude-sonnet-x","role":"assistant","content":[{"type":"text","text":"code"}],"usage":{"input_tokens":1000,"cache_creation_input_tokens":100000,"cache_read_input_tokens":500000,"output_tokens":20000}}}`,
+	} {
+		if _, err := m.parser.ParseLine([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// opus: 1.00 + 0.50; sonnet: 0.002 + 0.25 + 0.10 + 0.20
+	if got := m.costStatus(); got != "~$2.05" {
+		t.Errorf("unexpected cost status %q", got)
+	}
+
+	m = press(m, "s")
+	view := m.View()
+	for _, want := range []string{"opus-x", "sonnet-x", "$1.50", "$0.552", "$2.05"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("expected stats panel to contain %q:\n%s", want, view)
+		}
+	}
+
+	// Unknown models make the estimate a lower bound
+	m.parser.ParseLine([]byte(`{"type":"assistant","message":{"id":"msg_3","model":"local-llm","role":"assistant","content":[{"type":"text","text":"?"}],"usage":{"input_tokens":5,"output_tokens":5}}}`))
+	if got := m.costStatus(); got != "~$2.05+" {
+		t.Errorf("unexpected cost status with unpriced model %q", got)
+	}
+}
+
+func TestStatusBarShowsWatcherState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "s.jsonl")
+	if err := os.WriteFile(path, []byte(`{"type":"result","subtype":"success","num_turns":1}`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	w := watcher.New(path)
+	if err := w.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	m := newTestModel()
+	m.watcher = w
+	next, _ := m.Update(lineMsg{watcher: w, line: <-w.Lines()})
+	m = next.(Model)
+	if !strings.Contains(m.View(), "finished") {
+		t.Errorf("expected finished session in status bar:\n%s", m.View())
+	}
+}
+
+func TestNewSessionFileSwitchesWithNotice(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "7f3a9c2e-0000-4000-8000-000000000000.jsonl")
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := newTestModel(&model.DisplayEvent{Type: "user", Text: "old session"})
+	m.followsDir = true
+	next, _ := m.Update(sessionFileMsg{path: path})
+	m = next.(Model)
+	defer m.watcher.Stop()
+
+	if m.filename != path || len(m.events) != 0 {
+		t.Errorf("expected to switch to %s, still on %s", path, m.filename)
+	}
+	if !strings.Contains(m.View(), "switched to session 7f3a…") {
+		t.Errorf("expected switch notice:\n%s", m.View())
+	}
+}
+
+func TestNewSessionFileLeavesOtherTabsAlone(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "7f3a9c2e-0000-4000-8000-000000000000.jsonl")
+	if err := os.WriteFile(path, []byte(`{"type":"user","message":{"role":"user","content":"after clear"}}`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := newTestModel(&model.DisplayEvent{Type: "user", Text: "project session"})
+	m.followsDir = true
+	other := watcher.NewReader(strings.NewReader(""))
+	m.AddTab("other.jsonl", other)
+	m = press(m, "tab")
+	m.events = []*model.DisplayEvent{{Type: "user", Text: "unrelated session"}}
+
+	next, _ := m.Update(sessionFileMsg{path: path})
+	m = next.(Model)
+	if m.active != 1 || m.filename != "other.jsonl" || len(m.events) != 1 {
+		t.Fatalf("expected the tab on screen untouched, got tab %d %s %v", m.active, m.filename, m.events)
+	}
+	followed := m.tabs[0]
+	defer followed.stop()
+	if followed.filename != path || !followed.followsDir || len(followed.events) != 0 {
+		t.Fatalf("expected the following tab moved to %s, got %s", path, followed.filename)
+	}
+
+	// The new session's lines count as unread in the background
+	next, _ = m.Update(lineMsg{watcher: followed.watcher, line: <-followed.watcher.Lines()})
+	m = next.(Model)
+	if m.tabs[0].unread != 1 {
+		t.Errorf("expected the new session's event unread, got %d", m.tabs[0].unread)
+	}
+}
+
+func TestAgentFileErrorsReachDiagnostics(t *testing.T) {
+	dir := t.TempDir()
+	session := filepath.Join(dir, "session.jsonl")
+	for _, path := range []string{session, filepath.Join(dir, "agent-c0ffee00.jsonl")} {
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := newTestModel()
+	m.tab = newTab(session, watcher.New(session))
+	for _, line := range []string{
+		`{"type":"assistant","message":{"id":"msg_1","role":"assistant","content":[{"type":"tool_use","id":"toolu_task","name":"Task","input":{"prompt":"go"}}]}}`,
+		`{"type":"user","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_task","content":"done"}]},"toolUseResult":{"status":"completed","agentId":"c0ffee00"}}`,
+	} {
+		m.addLine(m.watcher, watcher.Line{Data: []byte(line)})
+	}
+
+	// The subagent transcript is waited on for both lines and errors
+	cmd := m.watchAgentFiles(0)
+	aw := m.agentWatchers["c0ffee00"]
+	if aw == nil || cmd == nil {
+		t.Fatal("expected the subagent transcript watched")
+	}
+	defer aw.Stop()
+	if batch, ok := cmd().(tea.BatchMsg); !ok || len(batch) != 2 {
+		t.Fatalf("expected a line and an error wait, got %#v", cmd())
+	}
+
+	next, _ := m.Update(errMsg{watcher: aw, err: fmt.Errorf("read failed")})
+	m = next.(Model)
+	if len(m.diagnostics) != 1 || !strings.Contains(m.renderDiagnostic(m.diagnostics[0]), "agent-c0ffee00.jsonl: read failed") {
+		t.Errorf("expected the subagent error in the diagnostics, got %v", m.diagnostics)
+	}
+}
+
+func TestStdinTabHasNoAgentFiles(t *testing.T) {
+	tb := newTab("stdin", watcher.NewReader(strings.NewReader("")))
+	for _, line := range []string{
+		`{"type":"assistant","message":{"id":"msg_1","role":"assistant","content":[{"type":"tool_use","id":"toolu_task","name":"Task","input":{"prompt":"go"}}]}}`,
+		`{"type":"user","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_task","content":"done"}]},"toolUseResult":{"status":"completed","agentId":"c0ffee00"}}`,
+	} {
+		tb.addLine(tb.watcher, watcher.Line{Data: []byte(line)})
+	}
+	if cmd := tb.watchAgentFiles(0); cmd != nil || len(tb.agentWatchers) != 0 {
+		t.Errorf("expected no subagent files looked for next to a stream, got %v", tb.agentWatchers)
+	}
+	if label := tb.label(); label != "stdin" {
+		t.Errorf("expected the tab labelled stdin, got %q", label)
+	}
+}
+
+func TestTabsTrackBackgroundSessions(t *testing.T) {
+	m := newTestModel(&model.DisplayEvent{Type: "user", Text: "first session"})
+	bg := watcher.NewReader(strings.NewReader(""))
+	m.AddTab("b.jsonl", bg)
+
+	next, _ := m.Update(lineMsg{watcher: bg, line: watcher.Line{Data: []byte(`{"type":"user","cwd":"/work/feature","message":{"role":"user","content":"second session"}}`)}})
+	m = next.(Model)
+	if len(m.events) != 1 || m.events[0].Text != "first session" {
+		t.Fatalf("background line changed the session on screen: %v", m.events)
+	}
+	if view := m.View(); !strings.Contains(view, "2 feature (1)") {
+		t.Errorf("expected unread count on background tab:\n%s", view)
+	}
+
+	m = press(m, "tab")
+	if m.active != 1 || len(m.events) != 1 || m.events[0].Text != "second session" {
+		t.Fatalf("expected second session on screen, got tab %d %v", m.active, m.events)
+	}
+	if view := m.View(); strings.Contains(view, "(1)") {
+		t.Errorf("expected unread count cleared:\n%s", view)
+	}
+
+	// The first tab kept its own state
+	m = press(m, "1")
+	if m.active != 0 || m.events[0].Text != "first session" {
+		t.Errorf("expected first session back, got %v", m.events)
+	}
+
+	m = press(m, "tab")
+	m = press(m, "ctrl+w")
+	if len(m.tabs) != 1 || m.events[0].Text != "first session" {
+		t.Errorf("expected one tab left, got %d", len(m.tabs))
+	}
+	if strings.Contains(m.View(), "1 test") {
+		t.Errorf("expected no tab bar for a single session:\n%s", m.View())
+	}
+}
+
+func TestBranchKeysSwitchBetweenRewinds(t *testing.T) {
+	m := newTestModel()
+	for _, line := range []string{
+		`{"type":"user","uuid":"u1","message":{"role":"user","content":"first prompt"}}`,
+		`{"type":"user","uuid":"u2","parentUuid":"u1","message":{"role":"user","content":"abandoned prompt"}}`,
+		`{"type":"user","uuid":"u3","parentUuid":"u1","message":{"role":"user","content":"edited prompt"}}`,
+	} {
+		next, _ := m.Update(lineMsg{line: watcher.Line{Data: []byte(line)}})
+		m = next.(Model)
+	}
+
+	view := m.View()
+	if strings.Contains(view, "abandoned prompt") || !strings.Contains(view, "edited prompt") {
+		t.Errorf("expected only the latest branch:\n%s", view)
+	}
+	if !strings.Contains(view, "⑂ 2/2") || !strings.Contains(view, "branch 2 of 2") {
+		t.Errorf("expected fork marker and branch status:\n%s", view)
+	}
+
+	m = press(m, "b")
+	view = m.View()
+	if !strings.Contains(view, "abandoned prompt") || strings.Contains(view, "edited prompt") {
+		t.Errorf("expected the other branch after b:\n%s", view)
+	}
+	if !strings.Contains(view, "⑂ 1/2") {
+		t.Errorf("expected branch status 1/2:\n%s", view)
+	}
+
+	// A new line on the latest branch doesn't pull the view back
+	next, _ := m.Update(lineMsg{line: watcher.Line{Data: []byte(`{"type":"user","uuid":"u4","parentUuid":"u3","message":{"role":"user","content":"more"}}`)}})
+	m = next.(Model)
+	if strings.Contains(m.View(), "more") {
+		t.Errorf("expected to stay on the chosen branch:\n%s", m.View())
+	}
+	m = press(m, "B")
+	if !strings.Contains(m.View(), "more") {
+		t.Errorf("expected the latest branch back:\n%s", m.View())
+	}
+}
+
+func TestForkedSessionGrowsWithoutRethreading(t *testing.T) {
+	m := newTestModel()
+	for _, line := range []string{
+		`{"type":"user","uuid":"u1","message":{"role":"user","content":"first prompt"}}`,
+		`{"type":"user","uuid":"u2","parentUuid":"u1","message":{"role":"user","content":"abandoned prompt"}}`,
+		`{"type":"user","uuid":"u3","parentUuid":"u1","message":{"role":"user","content":"edited prompt"}}`,
+	} {
+		next, _ := m.Update(lineMsg{line: watcher.Line{Data: []byte(line)}})
+		m = next.(Model)
+	}
+	m.followMode = false
+	m.cursor = 1
+	before := slices.Clone(m.events)
+
+	next, _ := m.Update(lineMsg{line: watcher.Line{Data: []byte(`{"type":"user","uuid":"u4","parentUuid":"u3","message":{"role":"user","content":"more"}}`)}})
+	m = next.(Model)
+	if len(m.events) != len(before)+1 || !slices.Equal(m.events[:len(before)], before) {
+		t.Errorf("expected the line appended to the same events, got %v", m.events)
+	}
+	if m.cursor != 1 {
+		t.Errorf("expected the cursor to stay put, got %d", m.cursor)
+	}
+}
+
+func TestCompactionKeysJumpBetweenBoundaries(t *testing.T) {
+	m := newTestModel(
+		&model.DisplayEvent{Type: "user", Text: "start"},
+		&model.DisplayEvent{Type: "compact", Subtype: "auto", PreTokens: 155000, Text: "Summary: login fixed"},
+		&model.DisplayEvent{Type: "user", Text: "more work"},
+		&model.DisplayEvent{Type: "compact", Subtype: "manual", PreTokens: 90000},
+		&model.DisplayEvent{Type: "user", Text: "the end"},
+	)
+	m.cursor = 0
+
+	m = press(m, "c")
+	if m.cursor != 1 {
+		t.Fatalf("expected first boundary selected, got %d", m.cursor)
+	}
+	m = press(m, "c")
+	if m.cursor != 3 {
+		t.Errorf("expected second boundary selected, got %d", m.cursor)
+	}
+	m = press(m, "C")
+	if m.cursor != 1 {
+		t.Errorf("expected to jump back to the first boundary, got %d", m.cursor)
+	}
+
+	view := m.View()
+	if !strings.Contains(view, "⊟ compacted (auto) · 155.0k tokens") || !strings.Contains(view, "Summary: login fixed") {
+		t.Errorf("expected compaction divider with summary:\n%s", view)
+	}
+}
+
+func TestDiagnosticsPaneListsProblems(t *testing.T) {
+	m := newTestModel()
+	if strings.Contains(m.View(), "⚠") {
+		t.Errorf("expected no warning badge without problems:\n%s", m.View())
+	}
+
+	next, _ := m.Update(lineMsg{line: watcher.Line{Data: []byte(`{"type":"assistant","mess`), Number: 3, Offset: 412}})
+	m = next.(Model)
+	w := watcher.New("test.jsonl")
+	m.watcher = w
+	next, _ = m.Update(errMsg{watcher: w, err: fmt.Errorf("too many open files")})
+	m = next.(Model)
+
+	if !strings.Contains(m.View(), "⚠ 2 warnings") {
+		t.Errorf("expected a warning badge in the status bar:\n%s", m.View())
+	}
+
+	m = press(m, "d")
+	view := m.View()
+	for _, want := range []string{"malformed", "412", "watcher", "too many open files"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("expected %q in diagnostics pane:\n%s", want, view)
+		}
+	}
+	m = press(m, "d")
+	if m.diag.open {
+		t.Error("expected d to close the diagnostics pane")
+	}
+}
+
+func TestRenderCacheFollowsEventChanges(t *testing.T) {
+	tool := &model.ToolUse{ID: "t1", Name: "Bash", Input: `{"command":"go test ./..."}`}
+	event := &model.DisplayEvent{Type: "assistant", ToolUse: tool}
+	c := make(renderCache)
+	o := renderOpts{width: 80}
+
+	first := c.render(event, o)
+	if again := c.render(event, o); &again[0] != &first[0] {
+		t.Error("expected an unchanged event to reuse its rendered lines")
+	}
+	tool.Result = &model.ToolResult{Content: "ok  clancy/ui"}
+	if got := strings.Join(c.render(event, o), "\n"); !strings.Contains(got, "clancy/ui") {
+		t.Errorf("expected the attached result to re-render the event:\n%s", got)
+	}
+	if got := c.render(event, renderOpts{width: 80, hideToolOutput: true}); strings.Contains(strings.Join(got, "\n"), "clancy/ui") {
+		t.Error("expected different options to re-render the event")
+	}
+
+	c.prune(nil)
+	if len(c) != 0 {
+		t.Errorf("expected pruning to forget events no longer shown, got %d", len(c))
+	}
+}
+
+func TestViewFillsExactlyTheTerminal(t *testing.T) {
+	for _, width := range []int{40, 80, 120, 160} {
+		m := newTestModel(
+			&model.DisplayEvent{Type: "user", Text: "fix the tests"},
+			&model.DisplayEvent{Type: "assistant", Text: strings.Repeat("a long answer ", 40)},
+		)
+		m.filename = "/home/someone/.claude/projects/-work-a-very-long-project-name/7f3a9c2e-0000-4000-8000-000000000000.jsonl"
+		m.width = width
+		m.AddTab("other.jsonl", nil)
+		m.diagnostics = append(m.diagnostics, model.Diagnostic{Kind: "malformed"})
+		m.showNotice("switched to session 7f3a…")
+
+		picker := m
+		picker.picker.open = true
+		views := map[string]Model{"events": m, "stats": press(m, "s"), "diagnostics": press(m, "d"), "picker": picker}
+		for name, v := range views {
+			if got := lipgloss.Height(v.View()); got != v.height {
+				t.Errorf("%s view at width %d is %d lines, expected %d:\n%s", name, width, got, v.height, v.View())
+			}
+		}
+	}
+}
+
+func TestResetLineStartsTheSessionOver(t *testing.T) {
+	m := newTestModel()
+	line := watcher.Line{Data: []byte(`{"type":"user","message":{"role":"user","content":"hello"}}`), Number: 1}
+	next, _ := m.Update(lineMsg{line: line})
+	m = next.(Model)
+	m.search.query = "hello"
+
+	// The file was recreated and is read again from the start
+	next, _ = m.Update(lineMsg{line: watcher.Line{Reset: true}})
+	m = next.(Model)
+	if len(m.events) != 0 {
+		t.Fatalf("expected a reset to forget the old events, got %d", len(m.events))
+	}
+	next, _ = m.Update(lineMsg{line: line})
+	m = next.(Model)
+	if len(m.events) != 1 || len(m.search.matches) != 1 {
+		t.Errorf("expected the re-read line once and still searched, got %d events, %d matches", len(m.events), len(m.search.matches))
+	}
+}
@@ -0,0 +1,73 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aquila/clancy/model"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// branchIndex returns the position of the branch on screen among the
+// conversation's branches, or -1 if it hasn't forked
+func (m Model) branchIndex(branches []string) int {
+	if m.branch == "" {
+		return len(branches) - 1
+	}
+	for i, b := range branches {
+		if b == m.branch {
+			return i
+		}
+	}
+	return -1
+}
+
+// switchBranch shows the next or previous branch of a rewound conversation.
+// Coming back to the latest branch follows it again as it grows.
+func (m *Model) switchBranch(dir int) {
+	branches := m.parser.Branches()
+	if len(branches) < 2 {
+		m.showNotice("no other branches")
+		return
+	}
+	i := (m.branchIndex(branches) + dir + len(branches)) % len(branches)
+	m.branch = branches[i]
+	if i == len(branches)-1 {
+		m.branch = ""
+	}
+	m.events = m.parser.Thread(m.branch)
+	m.rendered.prune(m.events)
+	m.showNotice(fmt.Sprintf("branch %d of %d", i+1, len(branches)))
+
+	if m.search.query != "" {
+		m.updateMatches()
+	}
+	if m.cursor >= len(m.events) {
+		m.cursor = m.lastEvent()
+	}
+	if m.followMode {
+		m.offset = m.maxOffset()
+		m.cursor = m.lastEvent()
+		return
+	}
+	m.scrollToCursor()
+}
+
+// branchStatus shows which branch is on screen once the conversation forked
+func (m Model) branchStatus() string {
+	if !m.parser.Forked() {
+		return ""
+	}
+	branches := m.parser.Branches()
+	if len(branches) < 2 {
+		return ""
+	}
+	return fmt.Sprintf("⑂ %d/%d", m.branchIndex(branches)+1, len(branches))
+}
+
+// renderFork renders the divider where the branch on screen leaves a fork
+func renderFork(event *model.DisplayEvent, o renderOpts) string {
+	label := fmt.Sprintf("⑂ %s · b/B to switch ", event.Text)
+	rule := strings.Repeat("─", max(o.width-4-ansi.StringWidth(label)-3, 0))
+	return eventStyle.Width(o.width).Render(forkStyle.Render("── " + label + rule))
+}
@@ -0,0 +1,98 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/aquila/clancy/model"
+)
+
+// eventState fingerprints the parts of an event that change after it's
+// parsed: streamed text and tool input, a result attaching to its call, a
+// compaction summary filling in, a fork divider counting another branch, or a
+// subagent run growing
+type eventState struct {
+	label               string
+	text, input, output int
+	partial, done       bool
+	agent               *model.Agent
+	agentLines          int
+	agentEvents         int
+}
+
+// stateOf returns the fingerprint of an event
+func stateOf(event *model.DisplayEvent) eventState {
+	s := eventState{text: len(event.Text), partial: event.Partial, agent: event.Agent}
+	if event.Type == "fork" {
+		s.label = event.Text
+	}
+	if tool := event.ToolUse; tool != nil {
+		s.input = len(tool.Input)
+		s.done = tool.Result != nil
+		if tool.Result != nil {
+			s.output = len(tool.Result.Content)
+		}
+		if tool.Agent != nil {
+			s.agent = tool.Agent
+		}
+	}
+	if event.ToolResult != nil {
+		s.output = len(event.ToolResult.Content)
+	}
+	if s.agent != nil {
+		s.agentLines, s.agentEvents = s.agent.Lines, len(s.agent.Events)
+	}
+	return s
+}
+
+// renderedEvent is an event's rendered lines with what they were rendered from
+type renderedEvent struct {
+	opts  renderOpts
+	state eventState
+	lines []string
+
+	// diffs keeps the diffs of the event's file changes, including those
+	// of a nested subagent run, so they're computed once per input
+	diffs map[*model.ToolUse]*fileDiff
+
+	// images holds the event's image previews encoded for the terminal
+	images map[imageKey]string
+}
+
+// renderCache holds each event's rendered lines, so a new line or key press
+// only re-renders the events it changed
+type renderCache map[*model.DisplayEvent]*renderedEvent
+
+// render returns the lines of an event, rendering it again only when the
+// options or the event changed since last time. The lines are shared; copy
+// them before modifying. Events that render empty return nil.
+func (c renderCache) render(event *model.DisplayEvent, o renderOpts) []string {
+	state := stateOf(event)
+	r, ok := c[event]
+	if ok && r.opts == o && r.state == state {
+		return r.lines
+	}
+	if !ok {
+		r = &renderedEvent{}
+		c[event] = r
+	}
+	r.opts, r.state, r.lines = o, state, nil
+	o.cached = r
+	if rendered := renderEvent(event, o); rendered != "" {
+		r.lines = strings.Split(rendered, "\n")
+	}
+	return r.lines
+}
+
+// prune forgets events no longer on screen, such as the dividers of a
+// rebuilt branch
+func (c renderCache) prune(events []*model.DisplayEvent) {
+	keep := make(map[*model.DisplayEvent]bool, len(events))
+	for _, event := range events {
+		keep[event] = true
+	}
+	for event := range c {
+		if !keep[event] {
+			delete(c, event)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package ui
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/aquila/clancy/model"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// diagPanel is the panel listing problems met reading the session
+type diagPanel struct {
+	open   bool
+	offset int
+}
+
+// watcherFailed records that a transcript couldn't be watched
+func (m *Model) watcherFailed(path string, err error) {
+	m.diagnostics = append(m.diagnostics, model.Diagnostic{
+		Position: model.Position{File: path},
+		Kind:     "watcher",
+		Message:  err.Error(),
+	})
+}
+
+// diagnosticStatus shows a warning badge in the status bar when anything
+// couldn't be read
+func (m Model) diagnosticStatus() string {
+	switch len(m.diagnostics) {
+	case 0:
+		return ""
+	case 1:
+		return "⚠ 1 warning"
+	}
+	return fmt.Sprintf("⚠ %d warnings", len(m.diagnostics))
+}
+
+// updateDiagnostics handles keys while the diagnostics panel is open
+func (m Model) updateDiagnostics(msg tea.KeyMsg) (Model, tea.Cmd) {
+	switch msg.String() {
+	case "q", "ctrl+c":
+		return m.quit()
+	case "d", "esc":
+		m.diag.open = false
+	case "up", "k":
+		if m.diag.offset > 0 {
+			m.diag.offset--
+		}
+	case "down", "j":
+		if m.diag.offset < m.maxDiagOffset() {
+			m.diag.offset++
+		}
+	case "g", "home":
+		m.diag.offset = 0
+	case "G", "end":
+		m.diag.offset = m.maxDiagOffset()
+	}
+	return m, nil
+}
+
+// diagHeight returns the number of rows that fit on screen
+func (m Model) diagHeight() int {
+	// Status bar, table header and help bar
+	return max(m.height-3, 1)
+}
+
+// maxDiagOffset returns the offset that shows the newest problems
+func (m Model) maxDiagOffset() int {
+	return max(len(m.diagnostics)-m.diagHeight(), 0)
+}
+
+// diagRow is the layout of a diagnostics panel row
+const diagRow = "  %6s  %9s  %-13s  %s"
+
+// renderDiagnostic renders one problem, naming the file when it isn't the
+// session itself, e.g. a subagent transcript
+func (m Model) renderDiagnostic(d model.Diagnostic) string {
+	line, offset := "", ""
+	if d.Line > 0 {
+		line, offset = fmt.Sprint(d.Line), fmt.Sprint(d.Offset)
+	}
+	message := d.Message
+	if d.File != "" && d.File != m.filename {
+		message = filepath.Base(d.File) + ": " + message
+	}
+	return fmt.Sprintf(diagRow, line, offset, d.Kind, message)
+}
+
+// viewDiagnostics renders the problems met reading the session, oldest first
+func (m Model) viewDiagnostics() string {
+	var b strings.Builder
+	b.WriteString(renderStatusBar(m.filename, len(m.events), m.width, m.watcherStatus(), m.diagnosticStatus()))
+	b.WriteString("\n")
+	header := fmt.Sprintf(diagRow, "line", "offset", "kind", "problem")
+	b.WriteString(usageStyle.Render(ansi.Truncate(header, m.width, "…")))
+	b.WriteString("\n")
+
+	var rows []string
+	if len(m.diagnostics) == 0 {
+		rows = append(rows, usageStyle.Render("  Every line was read without problems"))
+	}
+	end := min(m.diag.offset+m.diagHeight(), len(m.diagnostics))
+	for _, d := range m.diagnostics[m.diag.offset:end] {
+		style := textStyle
+		if d.Kind == "malformed" || d.Kind == "watcher" {
+			style = errorStyle
+		}
+		rows = append(rows, style.Render(ansi.Truncate(m.renderDiagnostic(d), m.width, "…")))
+	}
+	for len(rows) < m.diagHeight() {
+		rows = append(rows, "")
+	}
+	b.WriteString(strings.Join(rows, "\n"))
+	b.WriteString("\n")
+	b.WriteString(renderBar(helpBarStyle, "d/esc:close  ↑↓/jk:scroll  g/G:top/bottom  q:quit", m.width))
+	return b.String()
+}
@@ -0,0 +1,321 @@
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aquila/clancy/model"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// diffContext is the number of unchanged lines kept around each change
+const diffContext = 3
+
+// maxDiffCells caps the LCS table size; bigger inputs are shown as a full
+// replacement instead of a minimal diff
+const maxDiffCells = 4_000_000
+
+// diffOp is one line of a line-based diff
+type diffOp struct {
+	kind byte // ' ', '-' or '+'
+	text string
+}
+
+// diffLines computes a minimal line diff between a and b using the longest
+// common subsequence
+func diffLines(a, b []string) []diffOp {
+	// Trim common prefix and suffix to keep the table small
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var ops []diffOp
+	for _, line := range a[:prefix] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	ops = append(ops, diffMiddle(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	return ops
+}
+
+// diffMiddle diffs the differing middle section of two inputs
+func diffMiddle(a, b []string) []diffOp {
+	var ops []diffOp
+	if len(a)*len(b) > maxDiffCells || len(a) == 0 || len(b) == 0 {
+		for _, line := range a {
+			ops = append(ops, diffOp{'-', line})
+		}
+		for _, line := range b {
+			ops = append(ops, diffOp{'+', line})
+		}
+		return ops
+	}
+
+	// lcs[i][j] is the LCS length of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+	return ops
+}
+
+// splitDiffLines splits text into lines, treating "" as no lines at all
+func splitDiffLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// diffStats counts added and removed lines
+func diffStats(ops []diffOp) (added, removed int) {
+	for _, op := range ops {
+		switch op.kind {
+		case '+':
+			added++
+		case '-':
+			removed++
+		}
+	}
+	return added, removed
+}
+
+// unifiedDiff formats diff ops as unified diff hunks with context lines
+func unifiedDiff(ops []diffOp) []string {
+	// Mark which ops fall within diffContext of a change
+	keep := make([]bool, len(ops))
+	for i, op := range ops {
+		if op.kind == ' ' {
+			continue
+		}
+		for k := i - diffContext; k <= i+diffContext; k++ {
+			if k >= 0 && k < len(ops) {
+				keep[k] = true
+			}
+		}
+	}
+
+	var lines []string
+	oldLine, newLine := 1, 1
+	for i := 0; i < len(ops); {
+		if !keep[i] {
+			if ops[i].kind != '+' {
+				oldLine++
+			}
+			if ops[i].kind != '-' {
+				newLine++
+			}
+			i++
+			continue
+		}
+
+		// Collect a hunk of consecutive kept ops
+		oldStart, newStart := oldLine, newLine
+		var body []string
+		for ; i < len(ops) && keep[i]; i++ {
+			op := ops[i]
+			body = append(body, string(op.kind)+op.text)
+			if op.kind != '+' {
+				oldLine++
+			}
+			if op.kind != '-' {
+				newLine++
+			}
+		}
+		lines = append(lines, fmt.Sprintf("@@ -%s +%s @@", hunkRange(oldStart, oldLine-oldStart), hunkRange(newStart, newLine-newStart)))
+		lines = append(lines, body...)
+	}
+	return lines
+}
+
+// hunkRange formats a unified diff range like "12,4"
+func hunkRange(start, count int) string {
+	if count == 0 {
+		start-- // empty ranges point at the line before
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// renderDiffLines colors unified diff lines, cutting them to width. With a
+// highlighter the code after the +/- marker is syntax highlighted.
+func renderDiffLines(lines []string, width int, h *highlighter) []string {
+	rendered := make([]string, 0, len(lines))
+	for _, line := range lines {
+		line = strings.ReplaceAll(line, "\t", "    ")
+		if h != nil && line != "" && strings.IndexByte(" +-", line[0]) >= 0 && !strings.HasPrefix(line, "@@") {
+			marker := toolInputStyle.Render(line[:1])
+			switch line[0] {
+			case '+':
+				marker = diffAddStyle.Render("+")
+			case '-':
+				marker = diffDelStyle.Render("-")
+			}
+			rendered = append(rendered, "  "+ansi.Truncate(marker+h.line(line[1:]), width, "…"))
+			continue
+		}
+		line = ansi.Truncate(line, width, "…")
+		switch {
+		case strings.HasPrefix(line, "@@"):
+			line = diffHunkStyle.Render(line)
+		case strings.HasPrefix(line, "+"):
+			line = diffAddStyle.Render(line)
+		case strings.HasPrefix(line, "-"):
+			line = diffDelStyle.Render(line)
+		default:
+			line = toolInputStyle.Render(line)
+		}
+		rendered = append(rendered, "  "+line)
+	}
+	return rendered
+}
+
+// fileEdit is one old_string/new_string replacement
+type fileEdit struct {
+	OldString string `json:"old_string"`
+	NewString string `json:"new_string"`
+}
+
+// fileChangeInput covers the inputs of Edit, MultiEdit and Write
+type fileChangeInput struct {
+	FilePath  string     `json:"file_path"`
+	OldString string     `json:"old_string"` // Edit
+	NewString string     `json:"new_string"` // Edit
+	Edits     []fileEdit `json:"edits"`      // MultiEdit
+	Content   *string    `json:"content"`    // Write
+}
+
+// renderFileChange renders Edit, MultiEdit and Write calls as a colored
+// unified diff under a file path header with line counts. Returns "" when
+// the input can't be parsed so the caller falls back to raw JSON.
+func renderFileChange(in ToolInput) string {
+	var data fileChangeInput
+	if err := json.Unmarshal([]byte(in.Input), &data); err != nil || data.FilePath == "" {
+		return ""
+	}
+
+	var edits []fileEdit
+	switch in.Name {
+	case "Edit":
+		edits = []fileEdit{{OldString: data.OldString, NewString: data.NewString}}
+	case "MultiEdit":
+		edits = data.Edits
+	case "Write":
+		if data.Content == nil {
+			return ""
+		}
+		edits = []fileEdit{{NewString: *data.Content}}
+	}
+	if len(edits) == 0 {
+		return ""
+	}
+
+	var h *highlighter
+	if in.Highlight {
+		h = newHighlighter(detectLanguage(data.FilePath))
+	}
+	diff := in.fileDiff(edits)
+
+	header := "  " + textStyle.Render(data.FilePath) + "  " +
+		diffAddStyle.Render(fmt.Sprintf("+%d", diff.added)) + " " +
+		diffDelStyle.Render(fmt.Sprintf("-%d", diff.removed))
+	if in.Name == "Write" {
+		header += usageStyle.Render("  (write)")
+	}
+
+	// Clip before rendering; highlighting lines that aren't shown is wasted
+	shown := diff.lines
+	if !in.Expanded && len(shown) > 12 {
+		shown = shown[:12]
+	}
+	lines := renderDiffLines(shown, in.Width, h)
+	if len(shown) < len(diff.lines) {
+		lines = append(lines, "...")
+	}
+	return header + "\n" + strings.Join(lines, "\n")
+}
+
+// fileDiff is the unified diff of a file change with its line counts
+type fileDiff struct {
+	input          string // the tool input the diff was computed from
+	lines          []string
+	added, removed int
+}
+
+// fileDiff returns the diff of the call's edits, reusing the one kept on the
+// event's render cache entry while the input is unchanged
+func (in ToolInput) fileDiff(edits []fileEdit) *fileDiff {
+	if in.cached != nil && in.tool != nil {
+		if d := in.cached.diffs[in.tool]; d != nil && d.input == in.Input {
+			return d
+		}
+	}
+	d := diffFileEdits(edits)
+	d.input = in.Input
+	if in.cached != nil && in.tool != nil {
+		if in.cached.diffs == nil {
+			in.cached.diffs = make(map[*model.ToolUse]*fileDiff)
+		}
+		in.cached.diffs[in.tool] = d
+	}
+	return d
+}
+
+// diffFileEdits computes the unified diff of a file change's edits
+func diffFileEdits(edits []fileEdit) *fileDiff {
+	d := &fileDiff{}
+	for i, edit := range edits {
+		ops := diffLines(splitDiffLines(edit.OldString), splitDiffLines(edit.NewString))
+		a, r := diffStats(ops)
+		d.added += a
+		d.removed += r
+		if i > 0 {
+			d.lines = append(d.lines, "…")
+		}
+		d.lines = append(d.lines, unifiedDiff(ops)...)
+	}
+	return d
+}
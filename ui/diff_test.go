@@ -0,0 +1,124 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aquila/clancy/model"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	old := []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"}
+	updated := []string{"a", "b", "c", "d", "e", "F", "g", "h", "i", "j", "k"}
+
+	got := unifiedDiff(diffLines(old, updated))
+	want := []string{
+		"@@ -3,8 +3,9 @@",
+		" c", " d", " e", "-f", "+F", " g", " h", " i", " j", "+k",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected diff:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestUnifiedDiffSplitsDistantHunks(t *testing.T) {
+	var old, updated []string
+	for i := 0; i < 20; i++ {
+		line := string(rune('a' + i))
+		old = append(old, line)
+		updated = append(updated, line)
+	}
+	updated[1] = "B"
+	updated[18] = "S"
+
+	var hunks int
+	for _, line := range unifiedDiff(diffLines(old, updated)) {
+		if strings.HasPrefix(line, "@@") {
+			hunks++
+		}
+	}
+	if hunks != 2 {
+		t.Errorf("expected 2 hunks, got %d", hunks)
+	}
+}
+
+func TestRenderToolUseFileChanges(t *testing.T) {
+	tests := []struct {
+		name     string
+		tool     string
+		input    string
+		contains []string
+	}{
+		{
+			name:     "edit",
+			tool:     "Edit",
+			input:    `{"file_path":"/src/main.go","old_string":"foo()\nbar()","new_string":"foo()\nbaz()\nqux()"}`,
+			contains: []string{"/src/main.go", "+2", "-1", "-bar()", "+baz()", "+qux()"},
+		},
+		{
+			name:     "multi edit",
+			tool:     "MultiEdit",
+			input:    `{"file_path":"/src/a.go","edits":[{"old_string":"x","new_string":"y"},{"old_string":"p","new_string":"q"}]}`,
+			contains: []string{"/src/a.go", "+2", "-2", "-x", "+y", "-p", "+q"},
+		},
+		{
+			name:     "write",
+			tool:     "Write",
+			input:    `{"file_path":"/src/new.go","content":"package main\n\nfunc main() {}\n"}`,
+			contains: []string{"/src/new.go", "+3", "-0", "@@ -0,0 +1,3 @@", "+package main"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := &model.DisplayEvent{
+				Type:    "assistant",
+				ToolUse: &model.ToolUse{Name: tt.tool, Input: tt.input},
+			}
+			result := renderToolUse(event, renderOpts{width: 80})
+			for _, s := range tt.contains {
+				if !strings.Contains(result, s) {
+					t.Errorf("expected output to contain %q:\n%s", s, result)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderToolUseFileChangeFallback(t *testing.T) {
+	event := &model.DisplayEvent{
+		Type:    "assistant",
+		ToolUse: &model.ToolUse{Name: "Edit", Input: `{invalid`},
+	}
+	if result := renderToolUse(event, renderOpts{width: 80}); !strings.Contains(result, "{invalid") {
+		t.Error("expected fallback to raw input")
+	}
+}
+
+func TestFileChangeDiffComputedOnce(t *testing.T) {
+	event := &model.DisplayEvent{Type: "assistant", ToolUse: &model.ToolUse{
+		Name:  "Edit",
+		Input: `{"file_path":"main.go","old_string":"a\nb\nc","new_string":"a\nB\nc"}`,
+	}}
+	cache := make(renderCache)
+	cache.render(event, renderOpts{width: 40})
+	first := cache[event].diffs[event.ToolUse]
+	if first == nil || first.added != 1 || first.removed != 1 {
+		t.Fatalf("expected the diff to be kept on the cache entry, got %+v", first)
+	}
+	cache.render(event, renderOpts{width: 120})
+	if cache[event].diffs[event.ToolUse] != first {
+		t.Error("expected renders at any width to share the diff")
+	}
+
+	event.ToolUse.Input = `{"file_path":"main.go","old_string":"a","new_string":"b"}`
+	cache.render(event, renderOpts{width: 120})
+	if cache[event].diffs[event.ToolUse] == first {
+		t.Error("expected a changed input to be diffed again")
+	}
+
+	cache.prune(nil)
+	if len(cache) != 0 {
+		t.Error("expected pruning to drop the entry along with its diffs")
+	}
+}
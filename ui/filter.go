@@ -0,0 +1,136 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aquila/clancy/model"
+)
+
+// EventKinds lists the event kinds accepted by filters
+var EventKinds = []string{"user", "text", "thinking", "tool_use", "tool_result", "system", "result", "error", "agent", "compact", "unknown"}
+
+// ValidateKinds returns an error naming the first unknown event kind
+func ValidateKinds(kinds []string) error {
+	for _, kind := range kinds {
+		if !isKind(kind) {
+			return fmt.Errorf("unknown event kind %q (valid: %s)", kind, strings.Join(EventKinds, ", "))
+		}
+	}
+	return nil
+}
+
+func isKind(kind string) bool {
+	for _, k := range EventKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// eventKind classifies a display event for filtering
+func eventKind(event *model.DisplayEvent) string {
+	switch {
+	case event.ToolUse != nil:
+		return "tool_use"
+	case event.Type == "assistant":
+		return "text"
+	case event.Type == "summary":
+		return "compact"
+	default:
+		return event.Type
+	}
+}
+
+// filter decides which events are shown. When only is non-empty, just those
+// kinds are shown; hide then removes kinds from what remains.
+type filter struct {
+	hide map[string]bool
+	only map[string]bool
+}
+
+func newFilter(hide, only []string) filter {
+	f := filter{hide: make(map[string]bool), only: make(map[string]bool)}
+	for _, kind := range hide {
+		f.hide[kind] = true
+	}
+	for _, kind := range only {
+		f.only[kind] = true
+	}
+	return f
+}
+
+// active reports whether any filter is set
+func (f filter) active() bool {
+	return len(f.hide) > 0 || len(f.only) > 0
+}
+
+// shows reports whether a kind passes the filter
+func (f filter) shows(kind string) bool {
+	if len(f.only) > 0 && !f.only[kind] {
+		return false
+	}
+	return !f.hide[kind]
+}
+
+// visible reports whether an event passes the filter. Failed events also
+// count as error, and for only, a tool call paired with its result also counts
+// as tool_result.
+func (f filter) visible(event *model.DisplayEvent) bool {
+	kind := eventKind(event)
+	if f.hide[kind] || (f.hide["error"] && event.Failed()) {
+		return false
+	}
+	if len(f.only) == 0 || f.only[kind] {
+		return true
+	}
+	if kind == "tool_use" && event.ToolUse.Result != nil && f.only["tool_result"] {
+		return true
+	}
+	return f.only["error"] && event.Failed()
+}
+
+// showsOutput reports whether a tool call's result is rendered under it.
+// Failed calls keep their output when filtering for errors.
+func (f filter) showsOutput(event *model.DisplayEvent) bool {
+	if f.shows("tool_result") {
+		return true
+	}
+	return !f.hide["tool_result"] && f.only["error"] && event.Failed()
+}
+
+// toggle flips whether a kind is hidden
+func (f filter) toggle(kind string) {
+	if f.hide[kind] {
+		delete(f.hide, kind)
+	} else {
+		f.hide[kind] = true
+	}
+}
+
+// toggleOnly flips whether a kind is in the only set
+func (f filter) toggleOnly(kind string) {
+	if f.only[kind] {
+		delete(f.only, kind)
+	} else {
+		f.only[kind] = true
+	}
+}
+
+// status summarizes the active filter for the status bar
+func (f filter) status() string {
+	if !f.active() {
+		return ""
+	}
+	var parts []string
+	for kind := range f.only {
+		parts = append(parts, "+"+kind)
+	}
+	for kind := range f.hide {
+		parts = append(parts, "-"+kind)
+	}
+	sort.Strings(parts)
+	return "filter: " + strings.Join(parts, ",")
+}
@@ -0,0 +1,294 @@
+package ui
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// lineNumberPrefix matches the "    12→" gutter Claude Code adds to Read output
+var lineNumberPrefix = regexp.MustCompile(`^\s*\d+(→|\t)`)
+
+// language describes how to tokenize source code for highlighting
+type language struct {
+	keywords     map[string]bool
+	lineComments []string
+	blockComment [2]string // start and end, empty if unsupported
+	quotes       string    // string delimiters; '`' may span lines
+}
+
+func words(s string) map[string]bool {
+	m := make(map[string]bool)
+	for _, w := range strings.Fields(s) {
+		m[w] = true
+	}
+	return m
+}
+
+var cLike = words("if else for while do switch case default break continue return goto struct union enum typedef sizeof static const extern void int char long short float double unsigned signed bool true false NULL nullptr class public private protected virtual template typename namespace using new delete this")
+
+var languages = map[string]*language{
+	"go": {
+		keywords:     words("break case chan const continue default defer else fallthrough for func go goto if import interface map package range return select struct switch type var nil true false iota error string int int64 int32 uint uint64 byte rune bool float64 any"),
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       "\"'`",
+	},
+	"python": {
+		keywords:     words("and as assert async await break class continue def del elif else except finally for from global if import in is lambda nonlocal not or pass raise return try while with yield None True False self"),
+		lineComments: []string{"#"},
+		quotes:       "\"'",
+	},
+	"javascript": {
+		keywords:     words("async await break case catch class const continue debugger default delete do else export extends finally for from function if import in instanceof let new of return static super switch this throw try typeof var void while yield null undefined true false"),
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       "\"'`",
+	},
+	"typescript": {
+		keywords:     words("async await break case catch class const continue debugger default delete do else enum export extends finally for from function if implements import in instanceof interface let new of private protected public readonly return static super switch this throw try type typeof var void while yield null undefined true false string number boolean any unknown never"),
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       "\"'`",
+	},
+	"rust": {
+		keywords:     words("as async await break const continue crate dyn else enum extern false fn for if impl in let loop match mod move mut pub ref return self Self static struct super trait true type unsafe use where while Some None Ok Err String Vec Option Result"),
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       "\"",
+	},
+	"shell": {
+		keywords:     words("if then else elif fi for while until do done case esac in function return local export echo cd exit set unset source"),
+		lineComments: []string{"#"},
+		quotes:       "\"'",
+	},
+	"c": {
+		keywords:     cLike,
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       "\"'",
+	},
+	"java": {
+		keywords:     words("abstract boolean break byte case catch char class const continue default do double else enum extends final finally float for if implements import instanceof int interface long new package private protected public return short static super switch this throw throws try void while null true false var record"),
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       "\"'",
+	},
+	"ruby": {
+		keywords:     words("alias and begin break case class def do else elsif end ensure false for if in module next nil not or redo rescue retry return self super then true undef unless until when while yield require attr_accessor"),
+		lineComments: []string{"#"},
+		quotes:       "\"'",
+	},
+	"json": {
+		keywords: words("true false null"),
+		quotes:   "\"",
+	},
+	"yaml": {
+		keywords:     words("true false null yes no"),
+		lineComments: []string{"#"},
+		quotes:       "\"'",
+	},
+	"sql": {
+		keywords:     words("select from where insert into values update set delete create table drop alter add index primary key foreign references join left right inner outer on group by order having limit and or not null as distinct SELECT FROM WHERE INSERT INTO VALUES UPDATE SET DELETE CREATE TABLE DROP ALTER ADD INDEX PRIMARY KEY FOREIGN REFERENCES JOIN LEFT RIGHT INNER OUTER ON GROUP BY ORDER HAVING LIMIT AND OR NOT NULL AS DISTINCT"),
+		lineComments: []string{"--"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       "'\"",
+	},
+}
+
+// languageAliases maps fence info strings and file extensions to languages
+var languageAliases = map[string]string{
+	"go": "go", "golang": "go",
+	"py": "python", "python": "python",
+	"js": "javascript", "jsx": "javascript", "mjs": "javascript", "cjs": "javascript", "javascript": "javascript",
+	"ts": "typescript", "tsx": "typescript", "typescript": "typescript",
+	"rs": "rust", "rust": "rust",
+	"sh": "shell", "bash": "shell", "zsh": "shell", "shell": "shell", "console": "shell",
+	"c": "c", "h": "c", "cpp": "c", "cc": "c", "hpp": "c", "c++": "c",
+	"java": "java", "kt": "java", "kotlin": "java", "scala": "java", "cs": "java", "csharp": "java",
+	"rb": "ruby", "ruby": "ruby",
+	"json": "json", "jsonl": "json",
+	"yaml": "yaml", "yml": "yaml", "toml": "yaml",
+	"sql": "sql",
+}
+
+// detectLanguage resolves a fence info string or file path to a language
+// name, or "" when unknown
+func detectLanguage(nameOrPath string) string {
+	key := strings.ToLower(nameOrPath)
+	if lang, ok := languageAliases[key]; ok {
+		return lang
+	}
+	if ext := filepath.Ext(key); ext != "" {
+		return languageAliases[ext[1:]]
+	}
+	return ""
+}
+
+// highlighter colors source code line by line, carrying block comment and
+// multi-line string state between lines
+type highlighter struct {
+	lang      *language
+	inComment bool
+	inString  byte // open multi-line string delimiter, 0 if none
+}
+
+// newHighlighter returns a highlighter for a language name, or nil if the
+// language is unknown
+func newHighlighter(lang string) *highlighter {
+	l, ok := languages[lang]
+	if !ok {
+		return nil
+	}
+	return &highlighter{lang: l}
+}
+
+// line highlights a single line of code
+func (h *highlighter) line(s string) string {
+	var b strings.Builder
+	l := h.lang
+	i := 0
+
+	emit := func(style lipgloss.Style, text string) {
+		if text != "" {
+			b.WriteString(style.Render(text))
+		}
+	}
+
+	for i < len(s) {
+		rest := s[i:]
+
+		// Continue a block comment or multi-line string from a previous line
+		if h.inComment {
+			end := strings.Index(rest, l.blockComment[1])
+			if end < 0 {
+				emit(codeCommentStyle, rest)
+				return b.String()
+			}
+			end += len(l.blockComment[1])
+			emit(codeCommentStyle, rest[:end])
+			h.inComment = false
+			i += end
+			continue
+		}
+		if h.inString != 0 {
+			end := strings.IndexByte(rest, h.inString)
+			if end < 0 {
+				emit(codeStringStyle, rest)
+				return b.String()
+			}
+			emit(codeStringStyle, rest[:end+1])
+			h.inString = 0
+			i += end + 1
+			continue
+		}
+
+		// Comments
+		if hasAnyPrefix(rest, l.lineComments) {
+			emit(codeCommentStyle, rest)
+			return b.String()
+		}
+		if l.blockComment[0] != "" && strings.HasPrefix(rest, l.blockComment[0]) {
+			h.inComment = true
+			emit(codeCommentStyle, l.blockComment[0])
+			i += len(l.blockComment[0])
+			continue
+		}
+
+		c := s[i]
+		switch {
+		case strings.IndexByte(l.quotes, c) >= 0:
+			end := closingQuote(rest, c)
+			if end < 0 {
+				if c == '`' {
+					h.inString = c
+				}
+				emit(codeStringStyle, rest)
+				return b.String()
+			}
+			emit(codeStringStyle, rest[:end+1])
+			i += end + 1
+
+		case c >= '0' && c <= '9':
+			j := i
+			for j < len(s) && (isWordByte(s[j]) || s[j] == '.') {
+				j++
+			}
+			emit(codeNumberStyle, s[i:j])
+			i = j
+
+		case isWordByte(c):
+			j := i
+			for j < len(s) && isWordByte(s[j]) {
+				j++
+			}
+			word := s[i:j]
+			switch {
+			case l.keywords[word]:
+				emit(codeKeywordStyle, word)
+			case j < len(s) && s[j] == '(':
+				emit(codeFuncStyle, word)
+			default:
+				emit(textStyle, word)
+			}
+			i = j
+
+		default:
+			// Run of punctuation and whitespace up to the next token
+			j := i + 1
+			for j < len(s) && !isWordByte(s[j]) && strings.IndexByte(l.quotes, s[j]) < 0 &&
+				!hasAnyPrefix(s[j:], l.lineComments) &&
+				(l.blockComment[0] == "" || !strings.HasPrefix(s[j:], l.blockComment[0])) {
+				j++
+			}
+			emit(codePunctStyle, s[i:j])
+			i = j
+		}
+	}
+	return b.String()
+}
+
+// closingQuote returns the index of the quote closing the string that starts
+// at s[0], honoring backslash escapes, or -1 if it doesn't close on this line
+func closingQuote(s string, quote byte) int {
+	for j := 1; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			if quote != '`' {
+				j++
+			}
+		case quote:
+			return j
+		}
+	}
+	return -1
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= 0x80 || unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c))
+}
+
+// highlightCode highlights lines of code, cut to width. Line number gutters
+// from Read output are kept and dimmed.
+func highlightCode(lines []string, h *highlighter, width int) []string {
+	out := make([]string, len(lines))
+	for i, line := range lines {
+		gutter := lineNumberPrefix.FindString(line)
+		code := strings.ReplaceAll(line[len(gutter):], "\t", "    ")
+		out[i] = ansi.Truncate(usageStyle.Render(gutter)+h.line(code), width, "…")
+	}
+	return out
+}
@@ -0,0 +1,84 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	tests := map[string]string{
+		"go":                 "go",
+		"golang":             "go",
+		"/src/app/index.TSX": "typescript",
+		"script.py":          "python",
+		"bash":               "shell",
+		"README":             "",
+		"notes.txt":          "",
+	}
+	for input, want := range tests {
+		if got := detectLanguage(input); got != want {
+			t.Errorf("detectLanguage(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestHighlighterKeepsText(t *testing.T) {
+	h := newHighlighter("go")
+	lines := []string{
+		`package main // entry point`,
+		`/* multi-line`,
+		`   comment */ var s = "quoted \" string" + ` + "`raw`",
+		`func main() { fmt.Println(42, 'x') }`,
+	}
+	for _, line := range lines {
+		if got := ansi.Strip(h.line(line)); got != line {
+			t.Errorf("highlighting changed text:\n got %q\nwant %q", got, line)
+		}
+	}
+}
+
+func TestHighlighterCarriesStateAcrossLines(t *testing.T) {
+	h := newHighlighter("go")
+	h.line("x := 1 /* open")
+	if !h.inComment {
+		t.Error("expected block comment to stay open")
+	}
+	h.line("still comment */ y := 2")
+	if h.inComment {
+		t.Error("expected block comment to close")
+	}
+	h.line("s := `raw")
+	if h.inString != '`' {
+		t.Error("expected raw string to stay open")
+	}
+}
+
+func TestHighlightCodeKeepsReadGutter(t *testing.T) {
+	got := highlightCode([]string{"     1→package main", "    12\tfunc f() {}"}, newHighlighter("go"), 80)
+	if ansi.Strip(got[0]) != "     1→package main" {
+		t.Errorf("unexpected line %q", ansi.Strip(got[0]))
+	}
+	if ansi.Strip(got[1]) != "    12    func f() {}" {
+		t.Errorf("unexpected line %q", ansi.Strip(got[1]))
+	}
+}
+
+func TestNewHighlighterUnknownLanguage(t *testing.T) {
+	if newHighlighter("") != nil || newHighlighter("cobol") != nil {
+		t.Error("expected nil highlighter for unknown language")
+	}
+}
+
+func TestHighlightedToolOutputClipsAfterHighlighting(t *testing.T) {
+	content := "a := 1\nb := 2\nc := 3\nd := 4\ne := 5\n"
+	got := strings.Split(renderToolOutput(content, 80, renderOpts{width: 80}, "go", false), "\n")
+	if len(got) != 5 || got[4] != "  ..." {
+		t.Errorf("expected 4 lines and a plain clip marker, got %q", got)
+	}
+	expanded := renderToolOutput(content, 80, renderOpts{width: 80, expanded: true}, "go", false)
+	if strings.Contains(expanded, "...") || strings.Count(expanded, "\n") != 4 {
+		t.Errorf("expected every line when expanded, got %q", expanded)
+	}
+}
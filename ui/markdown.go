@@ -0,0 +1,266 @@
+package ui
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+// Markdown block patterns
+var (
+	mdHeading     = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	mdFence       = regexp.MustCompile("^\\s*(```+|~~~+)\\s*([^`\\s]*)")
+	mdRule        = regexp.MustCompile(`^\s*([-*_])(\s*([-*_]))+\s*$`)
+	mdBullet      = regexp.MustCompile(`^(\s*)([-*+])\s+(.*)$`)
+	mdOrdered     = regexp.MustCompile(`^(\s*)(\d+)[.)]\s+(.*)$`)
+	mdTask        = regexp.MustCompile(`^\[([ xX])\]\s+(.*)$`)
+	mdQuote       = regexp.MustCompile(`^\s*>\s?(.*)$`)
+	mdTableDelim  = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$`)
+	mdInlineToken = regexp.MustCompile("`[^`]+`|\\*\\*[^*]+\\*\\*|__[^_]+__|~~[^~]+~~|\\*[^*\\s][^*]*\\*|\\b_[^_\\s][^_]*_\\b|\\[[^\\]]+\\]\\([^)]+\\)")
+)
+
+// renderMarkdown renders Markdown source as styled terminal lines wrapped to
+// width. Line breaks inside paragraphs are kept, as in chat transcripts.
+// Fenced code is syntax highlighted when highlight is set.
+func renderMarkdown(src string, width int, highlight bool) []string {
+	if width < 10 {
+		width = 10
+	}
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	lines := strings.Split(strings.TrimSpace(src), "\n")
+
+	var out []string
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+
+		// Fenced code block, shown verbatim with its language label
+		if m := mdFence.FindStringSubmatch(line); m != nil {
+			fence := m[1]
+			var code []string
+			for i++; i < len(lines); i++ {
+				if strings.HasPrefix(strings.TrimSpace(lines[i]), fence[:3]) {
+					break
+				}
+				code = append(code, lines[i])
+			}
+			lang := ""
+			if highlight {
+				lang = detectLanguage(m[2])
+			}
+			out = append(out, renderCodeBlock(code, m[2], lang, width)...)
+			continue
+		}
+
+		// Table: a header row followed by a delimiter row
+		if strings.Contains(line, "|") && i+1 < len(lines) && mdTableDelim.MatchString(lines[i+1]) && strings.Contains(lines[i+1], "-") {
+			rows := [][]string{splitTableRow(line)}
+			for i += 2; i < len(lines) && strings.Contains(lines[i], "|"); i++ {
+				rows = append(rows, splitTableRow(lines[i]))
+			}
+			i--
+			out = append(out, renderTable(rows, width)...)
+			continue
+		}
+
+		switch {
+		case strings.TrimSpace(line) == "":
+			// Collapse runs of blank lines
+			if len(out) > 0 && out[len(out)-1] != "" {
+				out = append(out, "")
+			}
+
+		case mdHeading.MatchString(line):
+			m := mdHeading.FindStringSubmatch(line)
+			style := mdHeadingStyle
+			if len(m[1]) == 1 {
+				style = mdTitleStyle
+			}
+			out = append(out, wrapStyled(style.Render(ansi.Strip(renderInline(m[2]))), width)...)
+
+		case mdRule.MatchString(line):
+			out = append(out, usageStyle.Render(strings.Repeat("─", width)))
+
+		case mdQuote.MatchString(line):
+			text := mdQuote.FindStringSubmatch(line)[1]
+			for _, l := range wrapStyled(renderInline(text), width-2) {
+				out = append(out, usageStyle.Render("│ ")+thinkingStyle.Render(ansi.Strip(l)))
+			}
+
+		case mdBullet.MatchString(line):
+			m := mdBullet.FindStringSubmatch(line)
+			marker := "•"
+			text := m[3]
+			if t := mdTask.FindStringSubmatch(text); t != nil {
+				marker, text = "□", t[2]
+				if t[1] != " " {
+					marker = successStyle.Render("✓")
+				}
+			}
+			out = append(out, renderListItem(len(m[1]), marker, text, width)...)
+
+		case mdOrdered.MatchString(line):
+			m := mdOrdered.FindStringSubmatch(line)
+			out = append(out, renderListItem(len(m[1]), m[2]+".", m[3], width)...)
+
+		default:
+			out = append(out, wrapStyled(renderInline(line), width)...)
+		}
+	}
+
+	// Drop a trailing blank left by the source
+	for len(out) > 0 && out[len(out)-1] == "" {
+		out = out[:len(out)-1]
+	}
+	return out
+}
+
+// renderListItem renders a list item with a hanging indent
+func renderListItem(indent int, marker, text string, width int) []string {
+	prefix := strings.Repeat(" ", indent) + marker + " "
+	prefixWidth := ansi.StringWidth(prefix)
+	wrapped := wrapStyled(renderInline(text), width-prefixWidth)
+	for i := range wrapped {
+		if i == 0 {
+			wrapped[i] = toolNameStyle.Render(prefix) + wrapped[i]
+		} else {
+			wrapped[i] = strings.Repeat(" ", prefixWidth) + wrapped[i]
+		}
+	}
+	return wrapped
+}
+
+// renderCodeBlock renders fenced code with its info string as a label,
+// highlighted when lang is a known language. Lines are cut rather than
+// wrapped so indentation stays readable.
+func renderCodeBlock(code []string, label, lang string, width int) []string {
+	var out []string
+	if label != "" {
+		out = append(out, mdCodeLabelStyle.Render(label))
+	}
+	if h := newHighlighter(lang); h != nil {
+		for _, line := range highlightCode(code, h, width-2) {
+			out = append(out, "  "+line)
+		}
+		return out
+	}
+	for _, line := range code {
+		line = strings.ReplaceAll(line, "\t", "    ")
+		out = append(out, mdCodeBlockStyle.Render("  "+ansi.Truncate(line, width-2, "…")))
+	}
+	return out
+}
+
+// splitTableRow splits a Markdown table row into trimmed cells
+func splitTableRow(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	line = strings.TrimSuffix(line, "|")
+	cells := strings.Split(line, "|")
+	for i, cell := range cells {
+		cells[i] = renderInline(strings.TrimSpace(cell))
+	}
+	return cells
+}
+
+// renderTable lays out table rows with box-drawing separators, shrinking the
+// widest columns when the table doesn't fit
+func renderTable(rows [][]string, width int) []string {
+	cols := 0
+	for _, row := range rows {
+		if len(row) > cols {
+			cols = len(row)
+		}
+	}
+	widths := make([]int, cols)
+	for _, row := range rows {
+		for c, cell := range row {
+			if w := ansi.StringWidth(cell); w > widths[c] {
+				widths[c] = w
+			}
+		}
+	}
+
+	// Each column is padded by a space on both sides plus a separator
+	for total(widths)+3*cols > width {
+		widest := 0
+		for c := range widths {
+			if widths[c] > widths[widest] {
+				widest = c
+			}
+		}
+		if widths[widest] <= 3 {
+			break
+		}
+		widths[widest]--
+	}
+
+	renderRow := func(row []string, header bool) string {
+		cells := make([]string, cols)
+		for c := range cells {
+			cell := ""
+			if c < len(row) {
+				cell = ansi.Truncate(row[c], widths[c], "…")
+			}
+			if header {
+				cell = mdHeadingStyle.Render(ansi.Strip(cell))
+			}
+			cells[c] = " " + cell + strings.Repeat(" ", widths[c]-ansi.StringWidth(cell)) + " "
+		}
+		return strings.Join(cells, usageStyle.Render("│"))
+	}
+
+	out := []string{renderRow(rows[0], true)}
+	seps := make([]string, cols)
+	for c := range seps {
+		seps[c] = strings.Repeat("─", widths[c]+2)
+	}
+	out = append(out, usageStyle.Render(strings.Join(seps, "┼")))
+	for _, row := range rows[1:] {
+		out = append(out, renderRow(row, false))
+	}
+	return out
+}
+
+func total(ns []int) int {
+	sum := 0
+	for _, n := range ns {
+		sum += n
+	}
+	return sum
+}
+
+// renderInline applies inline Markdown: code spans, bold, italic,
+// strikethrough and links
+func renderInline(text string) string {
+	var b strings.Builder
+	last := 0
+	for _, loc := range mdInlineToken.FindAllStringIndex(text, -1) {
+		b.WriteString(textStyle.Render(text[last:loc[0]]))
+		token := text[loc[0]:loc[1]]
+		switch {
+		case strings.HasPrefix(token, "`"):
+			b.WriteString(mdCodeStyle.Render(token[1 : len(token)-1]))
+		case strings.HasPrefix(token, "**"), strings.HasPrefix(token, "__"):
+			b.WriteString(mdBoldStyle.Render(token[2 : len(token)-2]))
+		case strings.HasPrefix(token, "~~"):
+			b.WriteString(mdStrikeStyle.Render(token[2 : len(token)-2]))
+		case strings.HasPrefix(token, "["):
+			label, _, _ := strings.Cut(token[1:], "](")
+			b.WriteString(mdLinkStyle.Render(label))
+		default:
+			b.WriteString(mdItalicStyle.Render(token[1 : len(token)-1]))
+		}
+		last = loc[1]
+	}
+	b.WriteString(textStyle.Render(text[last:]))
+	return b.String()
+}
+
+// wrapStyled word-wraps styled text to width, returning one entry per line
+func wrapStyled(s string, width int) []string {
+	if width < 1 {
+		width = 1
+	}
+	return strings.Split(ansi.Wrap(s, width, ""), "\n")
+}
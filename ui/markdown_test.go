@@ -0,0 +1,47 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	src := "# Summary\n\nFixed **two bugs** in `parser.go`, see [docs](https://example.com).\n\n" +
+		"- [x] done\n- pending item\n1. first\n\n" +
+		"```go\nfunc main() {}\n```\n\n" +
+		"| File | Lines |\n|------|------:|\n| parser.go | 12 |\n"
+
+	got := ansi.Strip(strings.Join(renderMarkdown(src, 60, false), "\n"))
+
+	for _, want := range []string{
+		"Summary",
+		"Fixed two bugs in parser.go, see docs.",
+		"✓ done",
+		"• pending item",
+		"1. first",
+		"go\n  func main() {}",
+		" File      │ Lines ",
+		" parser.go │ 12    ",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected rendered markdown to contain %q:\n%s", want, got)
+		}
+	}
+	for _, raw := range []string{"**", "`", "](", "|---", "```", "# "} {
+		if strings.Contains(got, raw) {
+			t.Errorf("expected markdown syntax %q to be rendered away:\n%s", raw, got)
+		}
+	}
+}
+
+func TestRenderMarkdownWrapsToWidth(t *testing.T) {
+	src := "A paragraph with enough words to need several lines at this width.\n" +
+		"- a list item that also needs to wrap across lines"
+	for _, line := range renderMarkdown(src, 20, false) {
+		if w := ansi.StringWidth(line); w > 20 {
+			t.Errorf("line wider than 20 columns (%d): %q", w, line)
+		}
+	}
+}
@@ -0,0 +1,452 @@
+package ui
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"mime"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/aquila/clancy/model"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// graphics is the inline image protocol of the terminal
+type graphics int
+
+const (
+	noGraphics graphics = iota
+	kittyGraphics
+	sixelGraphics
+)
+
+// Inline previews take a fixed block of cells so an image keeps its place
+// whatever the terminal width
+const (
+	previewRows    = 8
+	previewMaxCols = 60
+	cellWidthPx    = 10 // typical cell size, for scaling sixel images
+	cellHeightPx   = 20
+)
+
+// detectGraphics guesses the terminal's image protocol from the environment.
+// CLANCY_GRAPHICS=kitty, sixel or none overrides the guess. Multiplexers
+// don't pass images through, so there's no preview inside tmux by default.
+func detectGraphics() graphics {
+	switch os.Getenv("CLANCY_GRAPHICS") {
+	case "kitty":
+		return kittyGraphics
+	case "sixel":
+		return sixelGraphics
+	case "none":
+		return noGraphics
+	}
+	if os.Getenv("TMUX") != "" {
+		return noGraphics
+	}
+	term, program := os.Getenv("TERM"), os.Getenv("TERM_PROGRAM")
+	switch {
+	case os.Getenv("KITTY_WINDOW_ID") != "" || term == "xterm-kitty" || program == "ghostty" || program == "WezTerm":
+		return kittyGraphics
+	case strings.Contains(term, "foot") || strings.Contains(term, "mlterm") || strings.Contains(term, "contour") || strings.Contains(term, "sixel"):
+		return sixelGraphics
+	}
+	return noGraphics
+}
+
+// formatBytes formats a size in bytes with a binary unit
+func formatBytes(n int) string {
+	switch {
+	case n < 1024:
+		return fmt.Sprintf("%d B", n)
+	case n < 1024*1024:
+		return fmt.Sprintf("%.1f KB", float64(n)/1024)
+	}
+	return fmt.Sprintf("%.1f MB", float64(n)/(1024*1024))
+}
+
+// renderAttachments renders a placeholder line per image or document, with
+// an inline preview of images when the terminal can show them
+func renderAttachments(attachments []*model.Attachment, o renderOpts) string {
+	var lines []string
+	for _, a := range attachments {
+		icon := "▣"
+		if a.Kind == "document" {
+			icon = "▤"
+		}
+		details := []string{}
+		if a.MediaType != "" {
+			details = append(details, a.MediaType)
+		}
+		if a.Title != "" {
+			details = append(details, fmt.Sprintf("%q", a.Title))
+		}
+		if a.Width > 0 {
+			details = append(details, fmt.Sprintf("%d×%d", a.Width, a.Height))
+		}
+		if len(a.Data) > 0 {
+			details = append(details, formatBytes(len(a.Data)))
+		}
+		if a.URL != "" {
+			details = append(details, a.URL)
+		}
+		label := a.Kind
+		if len(details) > 0 {
+			label += " " + strings.Join(details, " · ")
+		}
+		lines = append(lines, "  "+mediaStyle.Render(icon+" "+label))
+		lines = append(lines, renderPreview(a, o)...)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// previewSize returns the cells an image preview takes, keeping its aspect
+// ratio, or zero for attachments that can't be previewed
+func previewSize(a *model.Attachment) (cols, rows int) {
+	if a.Kind != "image" || a.Width == 0 || a.Height == 0 {
+		return 0, 0
+	}
+	rows = min(previewRows, (a.Height+cellHeightPx-1)/cellHeightPx)
+	cols = max(1, rows*cellHeightPx*a.Width/(a.Height*cellWidthPx))
+	if cols > previewMaxCols {
+		cols = previewMaxCols
+		rows = max(1, cols*cellWidthPx*a.Height/(a.Width*cellHeightPx))
+	}
+	return cols, rows
+}
+
+// renderPreview returns the lines of an inline image preview. The encoded
+// image is kept on the event's render cache entry, so there's no preview
+// without one.
+func renderPreview(a *model.Attachment, o renderOpts) []string {
+	cols, rows := previewSize(a)
+	if cols == 0 || o.graphics == noGraphics || o.cached == nil {
+		return nil
+	}
+	cols = min(cols, o.width-6)
+	if cols < 1 {
+		return nil
+	}
+	key := imageKey{imageID(a), cols, rows}
+	if o.encodeImage(a, key) == "" {
+		return nil
+	}
+	marker := fmt.Sprintf("%d:%d:%d", key.id, cols, rows)
+	switch o.graphics {
+	case kittyGraphics:
+		// Like sixel, the upload is swapped in by layout. See expandKitty.
+		return append([]string{kittyMarker + marker}, kittyPlaceholders(key.id, cols, rows)...)
+	case sixelGraphics:
+		// Styling would mangle the escape sequence, so the line holds a
+		// marker that layout swaps for it. See expandSixel.
+		lines := make([]string, rows+1)
+		lines[rows] = sixelMarker + marker
+		return lines
+	}
+	return nil
+}
+
+// imageKey identifies an image preview by image ID and size
+type imageKey struct {
+	id         uint32
+	cols, rows int
+}
+
+// encodeImage encodes an image preview for the terminal unless the event's
+// cache entry already holds it, since encoding on every frame would be slow.
+// Returns "" for images that can't be decoded.
+func (o renderOpts) encodeImage(a *model.Attachment, key imageKey) string {
+	if seq, ok := o.cached.images[key]; ok {
+		return seq
+	}
+	seq := ""
+	if decoded, _, err := image.Decode(bytes.NewReader(a.Data)); err == nil {
+		w, h := key.cols*cellWidthPx, key.rows*cellHeightPx
+		switch o.graphics {
+		case kittyGraphics:
+			// Scaled down, as the upload is sent again whenever its line
+			// is redrawn
+			var buf bytes.Buffer
+			if png.Encode(&buf, scaleImage(decoded, w, h)) == nil {
+				seq = kittyTransmit(key.id, buf.Bytes(), key.cols, key.rows)
+			}
+		case sixelGraphics:
+			seq = encodeSixel(decoded, w, h)
+		}
+	}
+	if o.cached.images == nil {
+		o.cached.images = make(map[imageKey]string)
+	}
+	o.cached.images[key] = seq
+	return seq
+}
+
+// parseImageMarker reads the image key that follows a marker
+func parseImageMarker(s string) imageKey {
+	var key imageKey
+	fmt.Sscanf(s, "%d:%d:%d", &key.id, &key.cols, &key.rows)
+	return key
+}
+
+// imageID identifies an image to the kitty protocol by its content
+func imageID(a *model.Attachment) uint32 {
+	id := crc32.ChecksumIEEE(a.Data) & 0xFFFFFF
+	if id == 0 {
+		id = 1
+	}
+	return id
+}
+
+// kittyDiacritics mark the row of a placeholder cell; see the kitty graphics
+// protocol's Unicode placeholders
+var kittyDiacritics = []rune{0x0305, 0x030D, 0x030E, 0x0310, 0x0312, 0x033D, 0x033E, 0x033F, 0x0346, 0x034A}
+
+// kittyPlaceholders returns lines of Unicode placeholder cells that kitty
+// fills with the image. They scroll and redraw like text, which suits a TUI.
+// The foreground color carries the image ID.
+func kittyPlaceholders(id uint32, cols, rows int) []string {
+	const placeholder = "\U0010EEEE"
+	color := fmt.Sprintf("\x1b[38;2;%d;%d;%dm", id>>16&0xFF, id>>8&0xFF, id&0xFF)
+	lines := make([]string, rows)
+	for r := range lines {
+		first := placeholder + string(kittyDiacritics[r]) + string(kittyDiacritics[0])
+		lines[r] = "  " + color + first + strings.Repeat(placeholder, cols-1) + "\x1b[39m"
+	}
+	return lines
+}
+
+// kittyTransmit returns the escape sequence that uploads a PNG image to
+// kitty with a virtual placement of cols×rows cells for its placeholders
+func kittyTransmit(id uint32, data []byte, cols, rows int) string {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	var b strings.Builder
+	for first := true; first || encoded != ""; first = false {
+		chunk := encoded[:min(4096, len(encoded))]
+		encoded = encoded[len(chunk):]
+		more := 0
+		if encoded != "" {
+			more = 1
+		}
+		if first {
+			fmt.Fprintf(&b, "\x1b_Gf=100,a=T,U=1,q=2,i=%d,c=%d,r=%d,m=%d;%s\x1b\\", id, cols, rows, more, chunk)
+		} else {
+			fmt.Fprintf(&b, "\x1b_Gm=%d;%s\x1b\\", more, chunk)
+		}
+	}
+	return b.String()
+}
+
+// kittyMarker starts the line above a kitty preview, followed by the image
+// ID and size
+const kittyMarker = "\uE000kitty:"
+
+// expandKitty replaces a kitty marker line with the image upload. The upload
+// travels with the frame rather than on its own, so it can't interleave with
+// the renderer's output; the line stays blank.
+func expandKitty(line string, images map[imageKey]string) string {
+	i := strings.Index(line, kittyMarker)
+	if i < 0 {
+		return line
+	}
+	return images[parseImageMarker(line[i+len(kittyMarker):])]
+}
+
+// scaleImage scales an image to w×h pixels by nearest neighbour
+func scaleImage(img image.Image, w, h int) image.Image {
+	bounds := img.Bounds()
+	scaled := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			scaled.Set(x, y, img.At(bounds.Min.X+x*bounds.Dx()/w, bounds.Min.Y+y*bounds.Dy()/h))
+		}
+	}
+	return scaled
+}
+
+// sixelMarker starts the line below a sixel preview, followed by the image
+// ID and size
+const sixelMarker = "\uE000sixel:"
+
+// expandSixel replaces a sixel marker line with the image. It's drawn from
+// the line below its rows, moving the cursor up, because every line written
+// after it would erase it.
+func expandSixel(line string, images map[imageKey]string) string {
+	i := strings.Index(line, sixelMarker)
+	if i < 0 {
+		return line
+	}
+	key := parseImageMarker(line[i+len(sixelMarker):])
+	seq := images[key]
+	if seq == "" {
+		return ""
+	}
+	return fmt.Sprintf("\x1b7\x1b[%dA\r\x1b[%dC%s\x1b8", key.rows, ansi.StringWidth(line[:i]), seq)
+}
+
+// isImageLine reports whether a line carries an image: a kitty upload or
+// placeholders, or a sixel sequence. Restyling it would destroy the image.
+func isImageLine(line string) bool {
+	return strings.Contains(line, "\x1b_G") || strings.Contains(line, "\U0010EEEE") || strings.Contains(line, "\x1bPq")
+}
+
+// encodeSixel scales an image to w×h pixels and encodes it as sixel graphics
+// with a 6×6×6 color cube palette
+func encodeSixel(img image.Image, w, h int) string {
+	bounds := img.Bounds()
+	// Nearest neighbour scaling into palette indexes
+	pixels := make([]int, w*h)
+	var inUse [216]bool
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			r, g, b, _ := img.At(bounds.Min.X+x*bounds.Dx()/w, bounds.Min.Y+y*bounds.Dy()/h).RGBA()
+			c := int(r>>8*6/256)*36 + int(g>>8*6/256)*6 + int(b>>8*6/256)
+			pixels[y*w+x] = c
+			inUse[c] = true
+		}
+	}
+	var used []int
+	for c, ok := range inUse {
+		if ok {
+			used = append(used, c)
+		}
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "\x1bPq\"1;1;%d;%d", w, h)
+	for _, c := range used {
+		fmt.Fprintf(&out, "#%d;2;%d;%d;%d", c, c/36*20, c/6%6*20, c%6*20)
+	}
+	for band := 0; band < h; band += 6 {
+		first := true
+		for _, c := range used {
+			row := make([]byte, w)
+			present := false
+			for x := 0; x < w; x++ {
+				bits := 0
+				for i := 0; i < 6 && band+i < h; i++ {
+					if pixels[(band+i)*w+x] == c {
+						bits |= 1 << i
+					}
+				}
+				row[x] = byte(63 + bits)
+				present = present || bits != 0
+			}
+			if !present {
+				continue
+			}
+			if !first {
+				out.WriteByte('$')
+			}
+			first = false
+			fmt.Fprintf(&out, "#%d", c)
+			writeSixelRuns(&out, row)
+		}
+		out.WriteByte('-')
+	}
+	out.WriteString("\x1b\\")
+	return out.String()
+}
+
+// writeSixelRuns writes a row of sixel characters with run-length encoding
+func writeSixelRuns(out *strings.Builder, row []byte) {
+	for i := 0; i < len(row); {
+		j := i
+		for j < len(row) && row[j] == row[i] {
+			j++
+		}
+		if n := j - i; n > 3 {
+			fmt.Fprintf(out, "!%d%c", n, row[i])
+		} else {
+			out.Write(row[i:j])
+		}
+		i = j
+	}
+}
+
+// sixelCursorUp matches the cursor movement that precedes a sixel preview
+var sixelCursorUp = regexp.MustCompile("\x1b7\x1b\\[(\\d+)A")
+
+// clipSixel drops sixel previews whose image would start above the
+// viewport, where it would be drawn over the status bar
+func clipSixel(lines []string) {
+	for i, line := range lines {
+		match := sixelCursorUp.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		var up int
+		fmt.Sscan(match[1], &up)
+		if up > i {
+			lines[i] = ""
+		}
+	}
+}
+
+// eventAttachments returns the images and documents of an event and of the
+// result of its tool call
+func eventAttachments(event *model.DisplayEvent) []*model.Attachment {
+	attachments := event.Attachments
+	if event.ToolResult != nil {
+		attachments = append(attachments, event.ToolResult.Attachments...)
+	}
+	if event.ToolUse != nil && event.ToolUse.Result != nil {
+		attachments = append(attachments, event.ToolUse.Result.Attachments...)
+	}
+	return attachments
+}
+
+// attachmentExt picks a file extension for a media type
+func attachmentExt(mediaType string) string {
+	switch mediaType {
+	case "image/png":
+		return ".png"
+	case "image/jpeg":
+		return ".jpg"
+	case "image/gif":
+		return ".gif"
+	case "image/webp":
+		return ".webp"
+	case "application/pdf":
+		return ".pdf"
+	case "text/plain":
+		return ".txt"
+	}
+	if exts, _ := mime.ExtensionsByType(mediaType); len(exts) > 0 {
+		return exts[0]
+	}
+	return ".bin"
+}
+
+// saveAttachments writes the selected event's images and documents to the
+// current directory, named after their content so saving twice is harmless
+func (m *Model) saveAttachments() {
+	if m.cursor >= len(m.events) {
+		return
+	}
+	var saved []string
+	for _, a := range eventAttachments(m.events[m.cursor]) {
+		if len(a.Data) == 0 {
+			continue
+		}
+		name := fmt.Sprintf("clancy-%s-%08x%s", a.Kind, crc32.ChecksumIEEE(a.Data), attachmentExt(a.MediaType))
+		if err := os.WriteFile(filepath.Join(m.saveDir, name), a.Data, 0644); err != nil {
+			m.showNotice("save failed: " + err.Error())
+			return
+		}
+		saved = append(saved, name)
+	}
+	switch len(saved) {
+	case 0:
+		m.showNotice("no image or document to save")
+	case 1:
+		m.showNotice("saved " + saved[0])
+	default:
+		m.showNotice(fmt.Sprintf("saved %d files: %s", len(saved), strings.Join(saved, ", ")))
+	}
+}
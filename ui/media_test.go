@@ -0,0 +1,118 @@
+package ui
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aquila/clancy/model"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// testImage returns an attachment holding a w×h PNG
+func testImage(t *testing.T, w, h int) *model.Attachment {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for x := 0; x < w; x++ {
+		img.Set(x, 0, color.RGBA{R: 255, A: 255})
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return &model.Attachment{Kind: "image", MediaType: "image/png", Data: buf.Bytes(), Width: w, Height: h}
+}
+
+func TestRenderAttachmentPlaceholders(t *testing.T) {
+	event := &model.DisplayEvent{
+		Type: "user",
+		Text: "look at this",
+		Attachments: []*model.Attachment{
+			{Kind: "image", MediaType: "image/png", Width: 1280, Height: 720, Data: make([]byte, 2048)},
+			{Kind: "document", MediaType: "application/pdf", Title: "spec", Data: make([]byte, 3*1024*1024)},
+		},
+	}
+	out := renderUser(event, renderOpts{width: 80})
+	for _, want := range []string{"▣ image image/png · 1280×720 · 2.0 KB", `▤ document application/pdf · "spec" · 3.0 MB`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in:\n%s", want, out)
+		}
+	}
+}
+
+func TestImagePreviews(t *testing.T) {
+	a := testImage(t, 400, 200)
+	if cols, rows := previewSize(a); cols != 32 || rows != 8 {
+		t.Errorf("expected 32×8 cells, got %d×%d", cols, rows)
+	}
+
+	cached := &renderedEvent{}
+	kitty := renderPreview(a, renderOpts{width: 80, graphics: kittyGraphics, cached: cached})
+	if len(kitty) != 9 || !strings.Contains(kitty[1], "\U0010EEEE") {
+		t.Fatalf("expected a line to upload from and 8 rows of kitty placeholders, got %q", kitty)
+	}
+	// The upload travels in the frame, sized to the placeholders
+	seq := expandKitty("  "+kitty[0], cached.images)
+	if !strings.HasPrefix(seq, fmt.Sprintf("\x1b_Gf=100,a=T,U=1,q=2,i=%d,c=32,r=8,", imageID(a))) || !strings.HasSuffix(seq, "\x1b\\") {
+		t.Errorf("unexpected kitty upload %.60q", seq)
+	}
+	if ansi.StringWidth(seq) != 0 {
+		t.Errorf("expected the upload line to stay blank, got width %d", ansi.StringWidth(seq))
+	}
+
+	cached = &renderedEvent{}
+	sixel := renderPreview(a, renderOpts{width: 80, graphics: sixelGraphics, cached: cached})
+	if len(sixel) != 9 {
+		t.Fatalf("expected 8 rows and a line to draw from, got %d lines", len(sixel))
+	}
+	sixel[8] = expandSixel("  "+sixel[8], cached.images)
+	if !strings.HasPrefix(sixel[8], "\x1b7\x1b[8A\r\x1b[2C\x1bPq") {
+		t.Fatalf("expected the sixel image drawn 8 rows up, got %.40q", sixel[8])
+	}
+	lines := append([]string{"status"}, sixel[3:]...)
+	clipSixel(lines)
+	if lines[len(lines)-1] != "" {
+		t.Error("expected a sixel image starting above the viewport to be dropped")
+	}
+
+	// A narrower terminal gets its own, smaller encoding
+	narrow := renderPreview(a, renderOpts{width: 26, graphics: sixelGraphics, cached: cached})
+	if len(narrow) != 9 || !strings.HasSuffix(narrow[8], ":20:8") {
+		t.Fatalf("expected a 20 column preview, got %q", narrow)
+	}
+	if cached.images[imageKey{imageID(a), 20, 8}] == cached.images[imageKey{imageID(a), 32, 8}] {
+		t.Error("expected the narrow preview to be encoded at its own width")
+	}
+
+	if renderPreview(a, renderOpts{width: 80, cached: cached}) != nil {
+		t.Error("expected no preview without terminal graphics")
+	}
+	if renderPreview(a, renderOpts{width: 80, graphics: kittyGraphics}) != nil {
+		t.Error("expected no preview without a cache entry to keep the image on")
+	}
+}
+
+func TestSaveKeyWritesAttachments(t *testing.T) {
+	a := testImage(t, 2, 2)
+	m := newTestModel(&model.DisplayEvent{Type: "user", Text: "screenshot", Attachments: []*model.Attachment{a}})
+	m.saveDir = t.TempDir()
+	m.cursor = 0
+
+	m = press(m, "w")
+	files, _ := filepath.Glob(filepath.Join(m.saveDir, "clancy-image-*.png"))
+	if len(files) != 1 {
+		t.Fatalf("expected one saved image, got %v", files)
+	}
+	if data, _ := os.ReadFile(files[0]); !bytes.Equal(data, a.Data) {
+		t.Error("saved image differs from the attachment")
+	}
+	if !strings.Contains(m.View(), "saved clancy-image-") {
+		t.Errorf("expected save notice:\n%s", m.View())
+	}
+}
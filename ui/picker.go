@@ -0,0 +1,235 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aquila/clancy/pricing"
+	"github.com/aquila/clancy/session"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// picker is the session browser screen
+type picker struct {
+	open        bool
+	allProjects bool // list every project instead of the current one
+	loading     bool
+	sessions    []session.Info
+	cursor      int
+	offset      int
+	err         error
+}
+
+// sessionsMsg carries the result of listing sessions
+type sessionsMsg struct {
+	allProjects bool
+	sessions    []session.Info
+	err         error
+}
+
+// loadSessions lists sessions of the current project, or of all projects
+func loadSessions(allProjects bool) tea.Cmd {
+	return func() tea.Msg {
+		var infos []session.Info
+		var err error
+		if allProjects {
+			var dir string
+			if dir, err = session.ProjectsDir(); err == nil {
+				infos, err = session.ListAll(dir)
+			}
+		} else {
+			var dir string
+			if dir, err = session.CurrentProjectDir(); err == nil {
+				infos, err = session.List(dir)
+			}
+		}
+		return sessionsMsg{allProjects: allProjects, sessions: infos, err: err}
+	}
+}
+
+// openPicker shows the picker and starts listing sessions
+func (m Model) openPicker(allProjects bool) (Model, tea.Cmd) {
+	m.picker.open = true
+	m.picker.allProjects = allProjects
+	m.picker.loading = true
+	return m, loadSessions(allProjects)
+}
+
+// updatePicker handles keys while the picker is open
+func (m Model) updatePicker(msg tea.KeyMsg) (Model, tea.Cmd) {
+	p := &m.picker
+	switch msg.String() {
+	case "q", "ctrl+c":
+		return m.quit()
+
+	case "esc", "p":
+		// Only close if there is a session to go back to
+		if m.watcher != nil {
+			p.open = false
+		}
+
+	case "a":
+		return m.openPicker(!p.allProjects)
+
+	case "up", "k":
+		if p.cursor > 0 {
+			p.cursor--
+		}
+
+	case "down", "j":
+		if p.cursor < len(p.sessions)-1 {
+			p.cursor++
+		}
+
+	case "pgup":
+		p.cursor -= m.pickerHeight()
+		if p.cursor < 0 {
+			p.cursor = 0
+		}
+
+	case "pgdown":
+		p.cursor += m.pickerHeight()
+		if p.cursor > len(p.sessions)-1 {
+			p.cursor = len(p.sessions) - 1
+		}
+		if p.cursor < 0 {
+			p.cursor = 0
+		}
+
+	case "g", "home":
+		p.cursor = 0
+
+	case "G", "end":
+		if len(p.sessions) > 0 {
+			p.cursor = len(p.sessions) - 1
+		}
+
+	case "enter":
+		if p.cursor < len(p.sessions) {
+			p.open = false
+			return m.openSession(p.sessions[p.cursor].Path)
+		}
+
+	case "t":
+		if p.cursor < len(p.sessions) {
+			p.open = false
+			if m.watcher == nil {
+				return m.openSession(p.sessions[p.cursor].Path)
+			}
+			return m.openTab(p.sessions[p.cursor].Path)
+		}
+	}
+
+	// Keep the cursor on screen
+	if p.cursor < p.offset {
+		p.offset = p.cursor
+	} else if h := m.pickerHeight(); p.cursor >= p.offset+h {
+		p.offset = p.cursor - h + 1
+	}
+	return m, nil
+}
+
+// pickerHeight returns the number of session rows that fit on screen
+func (m Model) pickerHeight() int {
+	// Status bar, picker header and help bar
+	h := m.height - 3
+	if h < 1 {
+		h = 1
+	}
+	return h
+}
+
+// viewPicker renders the session list
+func (m Model) viewPicker() string {
+	p := m.picker
+	var b strings.Builder
+
+	title := " sessions: current project"
+	if p.allProjects {
+		title = " sessions: all projects"
+	}
+	count := fmt.Sprintf("%d sessions ", len(p.sessions))
+	spaces := m.width - statusBarStyle.GetHorizontalFrameSize() - ansi.StringWidth(title) - ansi.StringWidth(count)
+	if spaces < 1 {
+		spaces = 1
+	}
+	b.WriteString(renderBar(statusBarStyle, title+strings.Repeat(" ", spaces)+count, m.width))
+	b.WriteString("\n")
+	b.WriteString(usageStyle.Render(fmt.Sprintf("  %-16s %5s  %-18s %-14s %8s  %s", "started", "msgs", "model", "branch", "cost", "first prompt")))
+	b.WriteString("\n")
+
+	var rows []string
+	switch {
+	case p.loading:
+		rows = append(rows, "  Loading sessions...")
+	case p.err != nil:
+		rows = append(rows, errorStyle.Render("  "+p.err.Error()))
+	case len(p.sessions) == 0:
+		rows = append(rows, "  No sessions found. Press a to list all projects.")
+	default:
+		end := p.offset + m.pickerHeight()
+		if end > len(p.sessions) {
+			end = len(p.sessions)
+		}
+		for i := p.offset; i < end; i++ {
+			row := renderSessionRow(p.sessions[i], p.allProjects, m.prices, m.width-2)
+			if i == p.cursor {
+				row = cursorStyle.Render("▎ ") + textStyle.Render(row)
+			} else {
+				row = "  " + toolInputStyle.Render(row)
+			}
+			rows = append(rows, row)
+		}
+	}
+	for len(rows) < m.pickerHeight() {
+		rows = append(rows, "")
+	}
+	b.WriteString(strings.Join(rows, "\n"))
+	b.WriteString("\n")
+
+	help := "enter:open  t:new tab  ↑↓/jk:move  a:all projects  "
+	if m.watcher != nil {
+		help += "esc:back  "
+	}
+	help += "q:quit"
+	b.WriteString(renderBar(helpBarStyle, help, m.width))
+	return b.String()
+}
+
+// renderSessionRow renders one session as a single line. Sessions without a
+// reported cost show one estimated from their token usage.
+func renderSessionRow(info session.Info, showProject bool, prices pricing.Table, width int) string {
+	cost := ""
+	if info.CostUSD > 0 {
+		cost = fmt.Sprintf("$%.2f", info.CostUSD)
+	} else if estimate := estimateSessionCost(info, prices); estimate > 0 {
+		cost = fmt.Sprintf("~$%.2f", estimate)
+	}
+	prompt := strings.Join(strings.Fields(info.FirstPrompt), " ")
+	if prompt == "" {
+		prompt = info.ID()
+	}
+	if showProject {
+		prompt = info.Project + "  " + prompt
+	}
+	row := fmt.Sprintf("%-16s %5d  %-18s %-14s %8s  %s",
+		info.StartTime.Local().Format("2006-01-02 15:04"),
+		info.Messages,
+		ansi.Truncate(strings.TrimPrefix(info.Model, "claude-"), 18, "…"),
+		ansi.Truncate(info.GitBranch, 14, "…"),
+		cost,
+		prompt,
+	)
+	return ansi.Truncate(row, width, "…")
+}
+
+// estimateSessionCost prices a session's token usage, skipping unknown models
+func estimateSessionCost(info session.Info, prices pricing.Table) float64 {
+	total := 0.0
+	for modelName, usage := range info.Usage {
+		cost, _ := prices.Cost(modelName, usage)
+		total += cost
+	}
+	return total
+}
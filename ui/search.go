@@ -0,0 +1,180 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aquila/clancy/model"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// search holds the state of an incremental session search
+type search struct {
+	typing  bool   // the query prompt is open
+	query   string // active query, empty when no search is active
+	matches []int  // indices of events matching query, in order
+	current int    // position in matches of the selected hit, -1 if none
+}
+
+// caseFold reports whether a query should match case-insensitively (smartcase)
+func caseFold(query string) bool {
+	return strings.ToLower(query) == query
+}
+
+// searchableText returns every piece of an event that search should look at
+func searchableText(event *model.DisplayEvent) string {
+	parts := []string{event.Text}
+	if tool := event.ToolUse; tool != nil {
+		parts = append(parts, tool.Name, tool.Input)
+		if tool.Result != nil {
+			parts = append(parts, tool.Result.Content)
+		}
+	}
+	if event.ToolResult != nil {
+		parts = append(parts, event.ToolResult.Content)
+	}
+	return strings.Join(parts, "\n")
+}
+
+// eventMatches reports whether an event contains the query
+func eventMatches(event *model.DisplayEvent, query string) bool {
+	text := searchableText(event)
+	if caseFold(query) {
+		text = strings.ToLower(text)
+	}
+	return strings.Contains(text, query)
+}
+
+// updateMatches recomputes the matching events, keeping the selected hit when
+// it still matches so streaming lines do not move the user around
+func (m *Model) updateMatches() {
+	s := &m.search
+	var selected = -1
+	if s.current >= 0 && s.current < len(s.matches) {
+		selected = s.matches[s.current]
+	}
+
+	s.matches = s.matches[:0]
+	s.current = -1
+	if s.query == "" {
+		return
+	}
+	for i, event := range m.events {
+		if m.filter.visible(event) && eventMatches(event, s.query) {
+			if i == selected {
+				s.current = len(s.matches)
+			}
+			s.matches = append(s.matches, i)
+		}
+	}
+}
+
+// jumpToMatch selects the next (dir > 0) or previous (dir < 0) match relative
+// to the cursor, optionally expanding it so the hit is visible
+func (m *Model) jumpToMatch(dir int, expand bool) {
+	s := &m.search
+	if len(s.matches) == 0 {
+		return
+	}
+
+	next := -1
+	if dir > 0 {
+		for i, idx := range s.matches {
+			if idx > m.cursor {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			next = 0 // wrap around
+		}
+	} else {
+		for i := len(s.matches) - 1; i >= 0; i-- {
+			if s.matches[i] < m.cursor {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			next = len(s.matches) - 1
+		}
+	}
+
+	s.current = next
+	m.cursor = s.matches[next]
+	if expand {
+		m.expanded[m.events[m.cursor]] = true
+	}
+	m.followMode = false
+	m.scrollToCursor()
+}
+
+// status renders the "3/17" hit counter for the status bar
+func (s search) status() string {
+	if s.query == "" {
+		return ""
+	}
+	if len(s.matches) == 0 {
+		return fmt.Sprintf("/%s: no matches", s.query)
+	}
+	current := 0
+	if s.current >= 0 {
+		current = s.current + 1
+	}
+	return fmt.Sprintf("/%s %d/%d", s.query, current, len(s.matches))
+}
+
+// highlightMatches re-renders a line with every occurrence of query marked.
+// Lines without a match are returned untouched; matching lines lose their
+// original styling so the highlight stays readable.
+func highlightMatches(line, query string) string {
+	if query == "" || isImageLine(line) {
+		return line
+	}
+	plain := ansi.Strip(line)
+	fold := caseFold(query)
+	if start, _ := findMatch(plain, query, fold); start < 0 {
+		return line
+	}
+
+	var b strings.Builder
+	for {
+		start, end := findMatch(plain, query, fold)
+		if start < 0 {
+			b.WriteString(plain)
+			break
+		}
+		b.WriteString(plain[:start])
+		b.WriteString(searchMatchStyle.Render(plain[start:end]))
+		plain = plain[end:]
+	}
+	return b.String()
+}
+
+// findMatch returns the byte range of the first occurrence of query in s, or
+// -1, -1. Case-insensitive matching compares rune by rune rather than
+// lowercasing s, which can change its byte length, e.g. for "Ⱥ" or "İ".
+func findMatch(s, query string, fold bool) (int, int) {
+	if !fold {
+		i := strings.Index(s, query)
+		if i < 0 {
+			return -1, -1
+		}
+		return i, i + len(query)
+	}
+	runes := utf8.RuneCountInString(query)
+	for start := 0; start < len(s); {
+		end := start
+		for n := 0; n < runes && end < len(s); n++ {
+			_, size := utf8.DecodeRuneInString(s[end:])
+			end += size
+		}
+		if strings.EqualFold(s[start:end], query) {
+			return start, end
+		}
+		_, size := utf8.DecodeRuneInString(s[start:])
+		start += size
+	}
+	return -1, -1
+}
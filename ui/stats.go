@@ -0,0 +1,243 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aquila/clancy/model"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// defaultContextWindow is the context window of current Claude models
+const defaultContextWindow = 200_000
+
+// stats is the token accounting panel
+type stats struct {
+	open   bool
+	offset int
+}
+
+// contextWindow returns the context window size for a model. Claude Code
+// marks the 1M token variants with a "[1m]" suffix.
+func contextWindow(modelName string) int {
+	if strings.HasSuffix(strings.ToLower(modelName), "[1m]") {
+		return 1_000_000
+	}
+	return defaultContextWindow
+}
+
+// formatTokens abbreviates a token count, e.g. 950, 12.3k or 1.2M
+func formatTokens(n int) string {
+	switch {
+	case n < 1000:
+		return fmt.Sprint(n)
+	case n < 1_000_000:
+		return fmt.Sprintf("%.1fk", float64(n)/1000)
+	}
+	return fmt.Sprintf("%.1fM", float64(n)/1_000_000)
+}
+
+// contextFill returns the share of the context window used by the latest
+// message, from 0 to 100
+func contextFill(mu *model.MessageUsage) int {
+	return mu.Usage.ContextTokens() * 100 / contextWindow(mu.Model)
+}
+
+// tokenStatus summarizes running token totals and the context window fill
+// for the status bar
+func (m Model) tokenStatus() string {
+	usage := m.parser.Usage()
+	if len(usage) == 0 {
+		return ""
+	}
+	total := m.parser.TotalUsage()
+	status := fmt.Sprintf("↑%s ↓%s", formatTokens(total.ContextTokens()), formatTokens(total.OutputTokens))
+
+	// Subagents have their own context, so the fill is the main conversation's
+	for i := len(usage) - 1; i >= 0; i-- {
+		if !usage[i].Sidechain {
+			return status + fmt.Sprintf(" ctx %d%%", contextFill(usage[i]))
+		}
+	}
+	return status
+}
+
+// formatCost formats a cost in USD, keeping cents visible for small amounts
+func formatCost(usd float64) string {
+	if usd < 1 {
+		return fmt.Sprintf("$%.3f", usd)
+	}
+	return fmt.Sprintf("$%.2f", usd)
+}
+
+// estimateCost sums the priced cost of messages. It reports false when any
+// message's model has no price, so the sum is a lower bound.
+func (m Model) estimateCost(usage []*model.MessageUsage) (float64, bool) {
+	total := 0.0
+	complete := true
+	for _, mu := range usage {
+		cost, ok := m.prices.Cost(mu.Model, mu.Usage)
+		total += cost
+		complete = complete && ok
+	}
+	return total, complete
+}
+
+// costStatus shows the estimated session cost for the status bar, falling
+// back to the cost reported by result events when nothing could be priced
+func (m Model) costStatus() string {
+	cost, complete := m.estimateCost(m.parser.Usage())
+	if cost == 0 {
+		reported := 0.0
+		for _, event := range m.events {
+			if event.Type == "result" {
+				reported += event.CostUSD
+			}
+		}
+		if reported > 0 {
+			return formatCost(reported)
+		}
+		return ""
+	}
+	status := "~" + formatCost(cost)
+	if !complete {
+		status += "+"
+	}
+	return status
+}
+
+// usageByModel sums usage per model, in order of first use
+func usageByModel(usage []*model.MessageUsage) []*model.MessageUsage {
+	var models []*model.MessageUsage
+	byModel := make(map[string]*model.MessageUsage)
+	for _, mu := range usage {
+		sub, ok := byModel[mu.Model]
+		if !ok {
+			sub = &model.MessageUsage{Model: mu.Model}
+			byModel[mu.Model] = sub
+			models = append(models, sub)
+		}
+		sub.Usage = sub.Usage.Add(mu.Usage)
+	}
+	return models
+}
+
+// updateStats handles keys while the stats panel is open
+func (m Model) updateStats(msg tea.KeyMsg) (Model, tea.Cmd) {
+	switch msg.String() {
+	case "q", "ctrl+c":
+		return m.quit()
+	case "s", "esc":
+		m.stats.open = false
+	case "up", "k":
+		if m.stats.offset > 0 {
+			m.stats.offset--
+		}
+	case "down", "j":
+		if m.stats.offset < m.maxStatsOffset() {
+			m.stats.offset++
+		}
+	case "g", "home":
+		m.stats.offset = 0
+	case "G", "end":
+		m.stats.offset = m.maxStatsOffset()
+	}
+	return m, nil
+}
+
+// statsHeight returns the number of message rows that fit on screen
+func (m Model) statsHeight() int {
+	// Status bar, table header, footer and help bar
+	h := m.height - 3 - len(m.statsFooter())
+	if h < 1 {
+		h = 1
+	}
+	return h
+}
+
+// maxStatsOffset returns the offset that shows the newest messages
+func (m Model) maxStatsOffset() int {
+	max := len(m.parser.Usage()) - m.statsHeight()
+	if max < 0 {
+		return 0
+	}
+	return max
+}
+
+// statsRow is the layout of a stats panel row
+const statsRow = "  %4s  %-20s %8s %8s %8s %8s %11s %8s  %s"
+
+// renderStatsRow renders one message, subtotal or total row
+func renderStatsRow(num, label string, u model.Usage, context, cost string) string {
+	return fmt.Sprintf(statsRow, num, ansi.Truncate(strings.TrimPrefix(label, "claude-"), 20, "…"),
+		formatTokens(u.InputTokens), formatTokens(u.CacheCreationInputTokens), formatTokens(u.CacheReadInputTokens),
+		formatTokens(u.OutputTokens), context, cost, u.ServiceTier)
+}
+
+// priced formats the estimated cost of a usage, or "?" if the model is unknown
+func (m Model) priced(modelName string, u model.Usage) string {
+	cost, ok := m.prices.Cost(modelName, u)
+	if !ok {
+		return "?"
+	}
+	return formatCost(cost)
+}
+
+// statsFooter renders the totals row, preceded by per-model subtotals when the
+// session mixes models
+func (m Model) statsFooter() []string {
+	usage := m.parser.Usage()
+	var footer []string
+	if models := usageByModel(usage); len(models) > 1 {
+		for _, sub := range models {
+			row := renderStatsRow("", sub.Model, sub.Usage, "", m.priced(sub.Model, sub.Usage))
+			footer = append(footer, toolInputStyle.Render(row))
+		}
+	}
+	total := m.parser.TotalUsage()
+	total.ServiceTier = ""
+	cost, complete := m.estimateCost(usage)
+	costStr := formatCost(cost)
+	if !complete {
+		costStr += "+"
+	}
+	row := renderStatsRow("", "total", total, formatTokens(total.ContextTokens()), costStr)
+	return append(footer, toolNameStyle.Render(row))
+}
+
+// viewStats renders the per-message token breakdown with running totals
+func (m Model) viewStats() string {
+	var b strings.Builder
+	b.WriteString(renderStatusBar(m.filename, len(m.events), m.width, m.tokenStatus(), m.costStatus()))
+	b.WriteString("\n")
+	header := fmt.Sprintf(statsRow, "#", "model", "input", "cache wr", "cache rd", "output", "context", "cost", "tier")
+	b.WriteString(usageStyle.Render(ansi.Truncate(header, m.width, "…")))
+	b.WriteString("\n")
+
+	usage := m.parser.Usage()
+	var rows []string
+	if len(usage) == 0 {
+		rows = append(rows, usageStyle.Render("  No token usage recorded yet"))
+	}
+	end := m.stats.offset + m.statsHeight()
+	if end > len(usage) {
+		end = len(usage)
+	}
+	for i := m.stats.offset; i < end; i++ {
+		mu := usage[i]
+		context := fmt.Sprintf("%s %d%%", formatTokens(mu.Usage.ContextTokens()), contextFill(mu))
+		row := renderStatsRow(fmt.Sprint(i+1), mu.Model, mu.Usage, context, m.priced(mu.Model, mu.Usage))
+		rows = append(rows, textStyle.Render(ansi.Truncate(row, m.width, "…")))
+	}
+	for len(rows) < m.statsHeight() {
+		rows = append(rows, "")
+	}
+	for _, row := range m.statsFooter() {
+		rows = append(rows, ansi.Truncate(row, m.width, "…"))
+	}
+	b.WriteString(strings.Join(rows, "\n"))
+	b.WriteString("\n")
+	b.WriteString(renderBar(helpBarStyle, "s/esc:close  ↑↓/jk:scroll  g/G:top/bottom  q:quit", m.width))
+	return b.String()
+}
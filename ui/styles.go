@@ -10,6 +10,9 @@ var (
 	text   = lipgloss.AdaptiveColor{Light: "#1a1a1a", Dark: "#dddddd"}
 	accent = lipgloss.AdaptiveColor{Light: "#7571F9", Dark: "#7571F9"}
 	err    = lipgloss.AdaptiveColor{Light: "#FF5F87", Dark: "#FF5F87"}
+	green  = lipgloss.AdaptiveColor{Light: "#04B575", Dark: "#04B575"}
+	amber  = lipgloss.AdaptiveColor{Light: "#B8860B", Dark: "#F2C94C"}
+	cyan   = lipgloss.AdaptiveColor{Light: "#1F7A8C", Dark: "#6FD3E8"}
 
 	// Status bar
 	statusBarStyle = lipgloss.NewStyle().
@@ -91,11 +94,89 @@ var (
 	usageStyle = lipgloss.NewStyle().
 			Foreground(muted)
 
+	// Diffs
+	diffAddStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.AdaptiveColor{Light: "#04B575", Dark: "#04B575"})
+
+	diffDelStyle = lipgloss.NewStyle().
+			Foreground(err)
+
+	diffHunkStyle = lipgloss.NewStyle().
+			Foreground(accent)
+
+	// Markdown
+	mdTitleStyle = lipgloss.NewStyle().
+			Foreground(accent).
+			Bold(true).
+			Underline(true)
+
+	mdHeadingStyle = lipgloss.NewStyle().
+			Foreground(accent).
+			Bold(true)
+
+	mdBoldStyle = lipgloss.NewStyle().
+			Foreground(text).
+			Bold(true)
+
+	mdItalicStyle = lipgloss.NewStyle().
+			Foreground(text).
+			Italic(true)
+
+	mdStrikeStyle = lipgloss.NewStyle().
+			Foreground(muted).
+			Strikethrough(true)
+
+	mdLinkStyle = lipgloss.NewStyle().
+			Foreground(accent).
+			Underline(true)
+
+	mdCodeStyle = lipgloss.NewStyle().
+			Foreground(accent).
+			Background(subtle)
+
+	mdCodeBlockStyle = lipgloss.NewStyle().
+				Foreground(text)
+
+	mdCodeLabelStyle = lipgloss.NewStyle().
+				Foreground(muted).
+				Italic(true)
+
+	// Syntax highlighting
+	codeKeywordStyle = lipgloss.NewStyle().
+				Foreground(accent).
+				Bold(true)
+
+	codeStringStyle = lipgloss.NewStyle().
+			Foreground(green)
+
+	codeNumberStyle = lipgloss.NewStyle().
+			Foreground(amber)
+
+	codeCommentStyle = lipgloss.NewStyle().
+				Foreground(muted).
+				Italic(true)
+
+	codeFuncStyle = lipgloss.NewStyle().
+			Foreground(cyan)
+
+	codePunctStyle = lipgloss.NewStyle().
+			Foreground(text)
+
 	// Event container
 	eventStyle = lipgloss.NewStyle().
 			PaddingLeft(2).
 			MarginBottom(1)
 
+	// Selected event gutter
+	cursorStyle = lipgloss.NewStyle().
+			Foreground(accent).
+			Bold(true)
+
+	// Search hits
+	searchMatchStyle = lipgloss.NewStyle().
+				Background(accent).
+				Foreground(lipgloss.AdaptiveColor{Light: "#FFFFFF", Dark: "#FFFFFF"})
+
 	// Follow mode indicator
 	followOnStyle = lipgloss.NewStyle().
 			Foreground(lipgloss.AdaptiveColor{Light: "#04B575", Dark: "#04B575"}).
@@ -103,4 +184,34 @@ var (
 
 	followOffStyle = lipgloss.NewStyle().
 			Foreground(muted)
+
+	// Session tabs
+	tabStyle = lipgloss.NewStyle().
+			Foreground(muted).
+			PaddingLeft(1)
+
+	activeTabStyle = lipgloss.NewStyle().
+			Foreground(accent).
+			Bold(true).
+			PaddingLeft(1)
+
+	idleStyle = lipgloss.NewStyle().
+			Foreground(amber)
+
+	// Divider where a rewound conversation forks
+	forkStyle = lipgloss.NewStyle().
+			Foreground(amber)
+
+	// Compaction dividers and conversation summaries
+	compactStyle = lipgloss.NewStyle().
+			Foreground(cyan).
+			Bold(true)
+
+	// Image and document placeholders
+	mediaStyle = lipgloss.NewStyle().
+			Foreground(cyan)
+
+	// Subagent runs nested under their Task call
+	agentStyle = lipgloss.NewStyle().
+			Foreground(cyan)
 )
@@ -0,0 +1,360 @@
+package ui
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aquila/clancy/model"
+	"github.com/aquila/clancy/parser"
+	"github.com/aquila/clancy/watcher"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// tab is the state of one watched session
+type tab struct {
+	filename   string
+	watcher    *watcher.Watcher
+	parser     *parser.Parser
+	events     []*model.DisplayEvent
+	offset     int // scroll offset
+	followMode bool
+
+	// Problems met reading the session: malformed lines, unknown types and
+	// watcher failures, oldest first
+	diagnostics []model.Diagnostic
+
+	// Event cursor and per-event expansion overrides of expandAll
+	cursor   int
+	expanded map[*model.DisplayEvent]bool
+
+	rendered renderCache
+
+	search search
+	unread int // events that arrived while the tab was in the background
+
+	// branch is the tip of the branch on screen after a rewind, or "" to
+	// follow the latest one
+	branch string
+
+	// Watchers of subagent transcripts written next to the session, by agent
+	// ID. A nil entry means no file was found.
+	agentWatchers map[string]*watcher.Watcher
+
+	// followsDir marks the tab that moves to new sessions appearing in the
+	// project directory, e.g. after /clear
+	followsDir bool
+}
+
+func newTab(filename string, w *watcher.Watcher) tab {
+	return tab{
+		filename:   filename,
+		watcher:    w,
+		parser:     parser.New(),
+		events:     make([]*model.DisplayEvent, 0),
+		followMode: true,
+		expanded:   make(map[*model.DisplayEvent]bool),
+		rendered:   make(renderCache),
+		search:     search{current: -1},
+
+		agentWatchers: make(map[string]*watcher.Watcher),
+	}
+}
+
+// addLine parses a line from a watcher into the tab's events and returns how
+// many were added. Once the conversation has forked, only lines that change
+// the branch on screen rebuild the events from it.
+func (t *tab) addLine(w *watcher.Watcher, line watcher.Line) int {
+	pos := model.Position{Line: line.Number, Offset: line.Offset}
+	if w != nil {
+		pos.File = w.Path()
+	}
+	events, found := t.parser.ParseLineAt(line.Data, pos)
+	t.diagnostics = append(t.diagnostics, found...)
+	if t.parser.Forked() {
+		var ok bool
+		if events, ok = t.parser.Extend(t.branch); !ok {
+			n := len(t.events)
+			t.rethread()
+			return max(len(t.events)-n, 0)
+		}
+	}
+	t.events = append(t.events, events...)
+	return len(events)
+}
+
+// rethread rebuilds the events from the branch on screen, keeping the cursor
+// on its event if that's still shown
+func (t *tab) rethread() {
+	var selected *model.DisplayEvent
+	if t.cursor < len(t.events) {
+		selected = t.events[t.cursor]
+	}
+	t.events = t.parser.Thread(t.branch)
+	t.rendered.prune(t.events)
+	if i := slices.Index(t.events, selected); i >= 0 {
+		t.cursor = i
+	}
+}
+
+// restart forgets everything read from the session when its file starts
+// over, keeping the watcher, the search query and problems with the watcher
+func (t *tab) restart() {
+	for _, aw := range t.agentWatchers {
+		if aw != nil {
+			aw.Stop()
+		}
+	}
+	fresh := newTab(t.filename, t.watcher)
+	fresh.followMode = t.followMode
+	fresh.followsDir = t.followsDir
+	fresh.unread = t.unread
+	fresh.search.query = t.search.query
+	for _, d := range t.diagnostics {
+		if d.Kind == "watcher" {
+			fresh.diagnostics = append(fresh.diagnostics, d)
+		}
+	}
+	*t = fresh
+}
+
+// AddTab opens another session in a background tab
+func (m *Model) AddTab(filename string, w *watcher.Watcher) {
+	m.tabs = append(m.tabs, newTab(filename, w))
+}
+
+// openTab starts watching a transcript in a new tab and switches to it
+func (m Model) openTab(path string) (Model, tea.Cmd) {
+	w := watcher.New(path)
+	w.IdleTimeout = m.idleTimeout
+	if err := w.Start(); err != nil {
+		m.watcherFailed(path, err)
+		return m, nil
+	}
+	m.AddTab(path, w)
+	m.switchTab(len(m.tabs) - 1)
+	return m, tea.Batch(waitForLine(w), waitForError(w))
+}
+
+// saveTab writes the session on screen back to its slot in tabs
+func (m *Model) saveTab() {
+	m.tabs[m.active] = m.tab
+}
+
+// switchTab puts tab i on screen, wrapping around at either end
+func (m *Model) switchTab(i int) {
+	if len(m.tabs) < 2 {
+		return
+	}
+	i = (i + len(m.tabs)) % len(m.tabs)
+	m.saveTab()
+	m.active = i
+	m.tab = m.tabs[i]
+	m.unread = 0
+	m.stats = stats{}
+	m.diag = diagPanel{}
+
+	// Catch up on whatever arrived in the background
+	if m.search.query != "" {
+		m.updateMatches()
+	}
+	if m.followMode {
+		m.offset = m.maxOffset()
+		m.cursor = m.lastEvent()
+	}
+}
+
+// closeTab stops the session on screen and shows the next one. The last tab
+// can't be closed.
+func (m *Model) closeTab() {
+	if len(m.tabs) < 2 {
+		return
+	}
+	m.stop()
+	m.tabs = append(m.tabs[:m.active:m.active], m.tabs[m.active+1:]...)
+	if m.active >= len(m.tabs) {
+		m.active = len(m.tabs) - 1
+	}
+	m.tab = m.tabs[m.active]
+	m.unread = 0
+	m.stats = stats{}
+	m.diag = diagPanel{}
+	if m.followMode {
+		m.offset = m.maxOffset()
+		m.cursor = m.lastEvent()
+	}
+}
+
+// followingTab returns the tab that follows the project directory, or -1 once
+// it has been closed
+func (m Model) followingTab() int {
+	for i, t := range m.tabs {
+		if i == m.active {
+			t = m.tab
+		}
+		if t.followsDir {
+			return i
+		}
+	}
+	return -1
+}
+
+// followSession moves the tab following the project directory to a new
+// session file. Other tabs are left alone; a background tab counts the new
+// session's events as unread.
+func (m Model) followSession(path string) (Model, tea.Cmd) {
+	i := m.followingTab()
+	switch {
+	case i < 0:
+		return m, nil
+	case i == m.active:
+		if path == m.filename {
+			return m, nil
+		}
+		m, cmd := m.openSession(path)
+		m.showNotice("switched to session " + shortSessionID(path))
+		return m, cmd
+	case path == m.tabs[i].filename:
+		return m, nil
+	}
+
+	w := watcher.New(path)
+	w.IdleTimeout = m.idleTimeout
+	if err := w.Start(); err != nil {
+		m.watcherFailed(path, err)
+		return m, nil
+	}
+	m.tabs[i].stop()
+	m.tabs[i] = newTab(path, w)
+	m.tabs[i].followsDir = true
+	return m, tea.Batch(waitForLine(w), waitForError(w))
+}
+
+// tabIndex finds the tab fed by a watcher, or -1
+func (m Model) tabIndex(w *watcher.Watcher) int {
+	for i, t := range m.tabs {
+		if i == m.active {
+			t = m.tab
+		}
+		if t.owns(w) {
+			return i
+		}
+	}
+	return -1
+}
+
+// owns reports whether a watcher feeds the tab, either with the session
+// itself or with one of its subagent transcripts
+func (t tab) owns(w *watcher.Watcher) bool {
+	if t.watcher == w {
+		return true
+	}
+	for _, aw := range t.agentWatchers {
+		if aw == w && aw != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// stop stops the tab's watchers
+func (t tab) stop() {
+	if t.watcher != nil {
+		t.watcher.Stop()
+	}
+	for _, aw := range t.agentWatchers {
+		if aw != nil {
+			aw.Stop()
+		}
+	}
+}
+
+// agentFiles returns where newer Claude Code versions may have written the
+// transcript of a subagent run
+func agentFiles(session, agentID string) []string {
+	dir := filepath.Dir(session)
+	name := "agent-" + agentID + ".jsonl"
+	return []string{
+		filepath.Join(dir, name),
+		filepath.Join(dir, strings.TrimSuffix(filepath.Base(session), ".jsonl"), "subagents", name),
+	}
+}
+
+// watchAgentFiles starts following the transcripts of subagent runs that the
+// session only knows by ID, feeding them to the tab's parser. A stream has no
+// directory to find them in.
+func (t *tab) watchAgentFiles(idleTimeout time.Duration) tea.Cmd {
+	if t.watcher == nil || t.watcher.Path() == "" {
+		return nil
+	}
+	var cmds []tea.Cmd
+	for _, agent := range t.parser.Agents() {
+		if _, seen := t.agentWatchers[agent.ID]; seen || agent.Lines > 0 || agent.ID == "" {
+			continue
+		}
+		t.agentWatchers[agent.ID] = nil
+		for _, path := range agentFiles(t.filename, agent.ID) {
+			w := watcher.New(path)
+			w.IdleTimeout = idleTimeout
+			if w.Start() == nil {
+				t.agentWatchers[agent.ID] = w
+				cmds = append(cmds, waitForLine(w), waitForError(w))
+				break
+			}
+		}
+	}
+	return tea.Batch(cmds...)
+}
+
+// label names a tab after the session's working directory, which tells
+// parallel worktrees apart, falling back to the file name
+func (t tab) label() string {
+	if cwd := t.parser.Cwd(); cwd != "" {
+		return filepath.Base(cwd)
+	}
+	switch {
+	case t.filename == "":
+		return "sessions"
+	case t.watcher != nil && t.watcher.Path() == "":
+		return "stdin"
+	}
+	return shortSessionID(t.filename)
+}
+
+// activity returns an indicator of whether the session is running, idle or
+// finished
+func (t tab) activity() string {
+	if t.watcher == nil {
+		return usageStyle.Render("·")
+	}
+	switch t.watcher.Status().Phase {
+	case watcher.Running:
+		return successStyle.Render("●")
+	case watcher.Idle:
+		return idleStyle.Render("○")
+	}
+	return usageStyle.Render("✓")
+}
+
+// renderTabBar renders one label per tab with its activity and unread count
+func (m Model) renderTabBar() string {
+	var labels []string
+	for i, t := range m.tabs {
+		if i == m.active {
+			t = m.tab
+		}
+		label := fmt.Sprintf("%d %s", i+1, ansi.Truncate(t.label(), 20, "…"))
+		if t.unread > 0 {
+			label += fmt.Sprintf(" (%d)", t.unread)
+		}
+		style := tabStyle
+		if i == m.active {
+			style = activeTabStyle
+		}
+		labels = append(labels, t.activity()+style.Render(label))
+	}
+	return ansi.Truncate(" "+strings.Join(labels, "  "), m.width, "…")
+}
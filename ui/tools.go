@@ -0,0 +1,234 @@
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aquila/clancy/model"
+)
+
+// ToolInput is what a ToolRenderer receives for a tool call
+type ToolInput struct {
+	Name      string // tool name as called, e.g. "Bash" or "mcp__github__create_issue"
+	Input     string // raw JSON input
+	Width     int    // available content width
+	Expanded  bool   // the event is expanded and should show everything
+	Highlight bool   // syntax highlighting is enabled
+
+	tool   *model.ToolUse // the call being rendered
+	cached *renderedEvent // render cache entry of the call's event, if any
+}
+
+// opts returns the render options matching the tool input
+func (in ToolInput) opts() renderOpts {
+	return renderOpts{width: in.Width, expanded: in.Expanded, highlight: in.Highlight}
+}
+
+// ToolRenderer renders the input of a tool call. It returns "" when it can't
+// handle the input, in which case the raw JSON is shown instead.
+type ToolRenderer func(in ToolInput) string
+
+// toolRenderers maps tool names to their renderers. Names ending in "*" match
+// any tool with that prefix.
+var toolRenderers = map[string]ToolRenderer{
+	"TodoWrite": func(in ToolInput) string { return renderTodoWriteInput(in.Input, in.Width) },
+	"Edit":      renderFileChange,
+	"MultiEdit": renderFileChange,
+	"Write":     renderFileChange,
+	"Bash":      renderBashInput,
+	"Read":      renderReadInput,
+	"Grep":      renderSearchInput,
+	"Glob":      renderSearchInput,
+	"WebFetch":  renderWebFetchInput,
+	"WebSearch": renderWebSearchInput,
+	"Task":      renderTaskInput,
+	"Agent":     renderTaskInput, // Task's newer name
+
+	// Server tools run by the API itself
+	"web_search": renderWebSearchInput,
+}
+
+// RegisterToolRenderer adds or replaces the renderer for a tool. Use a
+// trailing "*" to cover a family of tools, e.g. "mcp__github__*".
+func RegisterToolRenderer(name string, r ToolRenderer) {
+	toolRenderers[name] = r
+}
+
+// lookupToolRenderer finds the renderer for a tool, preferring an exact name
+// over the longest matching prefix pattern
+func lookupToolRenderer(name string) ToolRenderer {
+	if r, ok := toolRenderers[name]; ok {
+		return r
+	}
+	var best ToolRenderer
+	bestLen := -1
+	for pattern, r := range toolRenderers {
+		prefix, ok := strings.CutSuffix(pattern, "*")
+		if ok && strings.HasPrefix(name, prefix) && len(prefix) > bestLen {
+			best, bestLen = r, len(prefix)
+		}
+	}
+	return best
+}
+
+// renderToolInput renders a tool call's input with its registered renderer,
+// falling back to the raw JSON
+func renderToolInput(in ToolInput) string {
+	if r := lookupToolRenderer(in.Name); r != nil {
+		if body := r(in); body != "" {
+			return body
+		}
+	}
+	input := in.opts().truncate(in.Input, 150)
+	return "  " + toolInputStyle.Width(in.Width).Render(input)
+}
+
+// renderField renders a muted label followed by a value
+func renderField(label, value string, width int) string {
+	return "  " + usageStyle.Render(label) + " " + textStyle.Width(width-len(label)-1).Render(value)
+}
+
+// bashInput is the Bash tool input
+type bashInput struct {
+	Command     string `json:"command"`
+	Description string `json:"description"`
+}
+
+// renderBashInput renders "$ command" followed by its description
+func renderBashInput(in ToolInput) string {
+	var data bashInput
+	if err := json.Unmarshal([]byte(in.Input), &data); err != nil || data.Command == "" {
+		return ""
+	}
+	o := in.opts()
+	command := strings.Join(o.clipLines(strings.Split(data.Command, "\n"), 5), "\n")
+	lines := []string{"  " + toolNameStyle.Render("$") + " " + textStyle.Width(in.Width-2).Render(command)}
+	if data.Description != "" {
+		lines = append(lines, "  "+thinkingStyle.Width(in.Width).Render(data.Description))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// readInput is the Read tool input
+type readInput struct {
+	FilePath string `json:"file_path"`
+	Offset   int    `json:"offset"`
+	Limit    int    `json:"limit"`
+}
+
+// renderReadInput renders path:offset-limit as a line range
+func renderReadInput(in ToolInput) string {
+	var data readInput
+	if err := json.Unmarshal([]byte(in.Input), &data); err != nil || data.FilePath == "" {
+		return ""
+	}
+	location := data.FilePath
+	if data.Offset > 0 || data.Limit > 0 {
+		start := data.Offset
+		if start < 1 {
+			start = 1
+		}
+		end := ""
+		if data.Limit > 0 {
+			end = fmt.Sprint(start + data.Limit - 1)
+		}
+		location += fmt.Sprintf(":%d-%s", start, end)
+	}
+	return "  " + textStyle.Width(in.Width).Render(location)
+}
+
+// searchInput covers the Grep and Glob tool inputs
+type searchInput struct {
+	Pattern    string `json:"pattern"`
+	Path       string `json:"path"`
+	Glob       string `json:"glob"`
+	Type       string `json:"type"`
+	OutputMode string `json:"output_mode"`
+}
+
+// renderSearchInput renders the pattern and where it's searched
+func renderSearchInput(in ToolInput) string {
+	var data searchInput
+	if err := json.Unmarshal([]byte(in.Input), &data); err != nil || data.Pattern == "" {
+		return ""
+	}
+	lines := []string{"  " + toolNameStyle.Render(data.Pattern)}
+	if data.Path != "" {
+		lines = append(lines, renderField("in", data.Path, in.Width))
+	}
+	var extras []string
+	if data.Glob != "" {
+		extras = append(extras, "glob "+data.Glob)
+	}
+	if data.Type != "" {
+		extras = append(extras, "type "+data.Type)
+	}
+	if data.OutputMode != "" {
+		extras = append(extras, data.OutputMode)
+	}
+	if len(extras) > 0 {
+		lines = append(lines, "  "+usageStyle.Render(strings.Join(extras, " · ")))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// webFetchInput is the WebFetch tool input
+type webFetchInput struct {
+	URL    string `json:"url"`
+	Prompt string `json:"prompt"`
+}
+
+// renderWebFetchInput renders the URL and the extraction prompt
+func renderWebFetchInput(in ToolInput) string {
+	var data webFetchInput
+	if err := json.Unmarshal([]byte(in.Input), &data); err != nil || data.URL == "" {
+		return ""
+	}
+	lines := []string{"  " + textStyle.Width(in.Width).Render(data.URL)}
+	if data.Prompt != "" {
+		prompt := in.opts().truncate(data.Prompt, 150)
+		lines = append(lines, "  "+thinkingStyle.Width(in.Width).Render(prompt))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// webSearchInput is the input of the WebSearch tool and the web_search
+// server tool
+type webSearchInput struct {
+	Query string `json:"query"`
+}
+
+// renderWebSearchInput renders the search query
+func renderWebSearchInput(in ToolInput) string {
+	var data webSearchInput
+	if err := json.Unmarshal([]byte(in.Input), &data); err != nil || data.Query == "" {
+		return ""
+	}
+	return "  " + textStyle.Width(in.Width).Render("\""+data.Query+"\"")
+}
+
+// taskInput is the Task (subagent) tool input
+type taskInput struct {
+	SubagentType string `json:"subagent_type"`
+	Description  string `json:"description"`
+	Prompt       string `json:"prompt"`
+}
+
+// renderTaskInput renders the subagent type, description and prompt
+func renderTaskInput(in ToolInput) string {
+	var data taskInput
+	if err := json.Unmarshal([]byte(in.Input), &data); err != nil || data.Prompt == "" {
+		return ""
+	}
+	o := in.opts()
+	header := data.SubagentType
+	if header == "" {
+		header = "agent"
+	}
+	if data.Description != "" {
+		header += ": " + data.Description
+	}
+	prompt := strings.Join(o.clipLines(strings.Split(o.truncate(data.Prompt, 300), "\n"), 3), "\n")
+	return "  " + toolNameStyle.Render(header) + "\n  " + toolInputStyle.Width(in.Width).Render(prompt)
+}
@@ -0,0 +1,73 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aquila/clancy/model"
+)
+
+func TestRenderToolUseSpecializedRenderers(t *testing.T) {
+	tests := []struct {
+		tool     string
+		input    string
+		contains []string
+	}{
+		{"Bash", `{"command":"go test ./...","description":"Run tests"}`, []string{"$", "go test ./...", "Run tests"}},
+		{"Read", `{"file_path":"/src/main.go","offset":100,"limit":50}`, []string{"/src/main.go:100-149"}},
+		{"Read", `{"file_path":"/src/main.go"}`, []string{"/src/main.go"}},
+		{"Grep", `{"pattern":"func main","path":"/src","glob":"*.go"}`, []string{"func main", "/src", "glob *.go"}},
+		{"Glob", `{"pattern":"**/*.ts"}`, []string{"**/*.ts"}},
+		{"WebFetch", `{"url":"https://example.com/docs","prompt":"Summarize"}`, []string{"https://example.com/docs", "Summarize"}},
+		{"Task", `{"subagent_type":"Explore","description":"Find handlers","prompt":"Look for HTTP handlers"}`, []string{"Explore: Find handlers", "Look for HTTP handlers"}},
+		{"Agent", `{"subagent_type":"general-purpose","description":"Audit deps","prompt":"List outdated modules"}`, []string{"general-purpose: Audit deps", "List outdated modules"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tool, func(t *testing.T) {
+			event := &model.DisplayEvent{
+				Type:    "assistant",
+				ToolUse: &model.ToolUse{Name: tt.tool, Input: tt.input},
+			}
+			result := renderToolUse(event, renderOpts{width: 80})
+			if strings.Contains(result, "{") {
+				t.Errorf("expected no raw JSON in output:\n%s", result)
+			}
+			for _, s := range tt.contains {
+				if !strings.Contains(result, s) {
+					t.Errorf("expected output to contain %q:\n%s", s, result)
+				}
+			}
+		})
+	}
+}
+
+func TestRegisterToolRenderer(t *testing.T) {
+	defer delete(toolRenderers, "mcp__tracker__*")
+	defer delete(toolRenderers, "mcp__tracker__create_issue")
+
+	RegisterToolRenderer("mcp__tracker__*", func(in ToolInput) string {
+		return "  tracker call"
+	})
+	RegisterToolRenderer("mcp__tracker__create_issue", func(in ToolInput) string {
+		return "  new issue"
+	})
+
+	render := func(name string) string {
+		event := &model.DisplayEvent{
+			Type:    "assistant",
+			ToolUse: &model.ToolUse{Name: name, Input: `{"title":"bug"}`},
+		}
+		return renderToolUse(event, renderOpts{width: 80})
+	}
+
+	if result := render("mcp__tracker__create_issue"); !strings.Contains(result, "new issue") {
+		t.Errorf("expected exact renderer, got:\n%s", result)
+	}
+	if result := render("mcp__tracker__list_issues"); !strings.Contains(result, "tracker call") {
+		t.Errorf("expected prefix renderer, got:\n%s", result)
+	}
+	if result := render("mcp__other__tool"); !strings.Contains(result, `{"title":"bug"}`) {
+		t.Errorf("expected raw JSON fallback, got:\n%s", result)
+	}
+}
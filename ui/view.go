@@ -4,89 +4,177 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/aquila/clancy/model"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
 )
 
+// renderOpts carries the per-event settings renderers need
+type renderOpts struct {
+	width     int
+	expanded  bool // show full content instead of the collapsed preview
+	highlight bool // syntax highlight code
+
+	hideToolOutput bool // render tool calls without their results
+
+	graphics graphics // inline image protocol, if the terminal has one
+
+	cached *renderedEvent // the event's render cache entry, if it has one
+}
+
+// truncate cuts s to max bytes unless the event is expanded
+func (o renderOpts) truncate(s string, max int) string {
+	if o.expanded || len(s) <= max {
+		return s
+	}
+	// Back up to a rune boundary so multi-byte characters stay intact
+	for max > 0 && !utf8.RuneStart(s[max]) {
+		max--
+	}
+	return s[:max] + "..."
+}
+
+// clipLines keeps the first max lines unless the event is expanded
+func (o renderOpts) clipLines(lines []string, max int) []string {
+	if o.expanded || len(lines) <= max {
+		return lines
+	}
+	return append(lines[:max:max], "...")
+}
+
 // renderEvent renders a single display event
-func renderEvent(event *model.DisplayEvent, width int) string {
+func renderEvent(event *model.DisplayEvent, o renderOpts) string {
 	switch event.Type {
 	case "system":
-		return renderSystem(event, width)
+		return renderSystem(event, o)
 	case "assistant":
 		if event.ToolUse != nil {
-			return renderToolUse(event, width)
+			return renderToolUse(event, o)
 		}
-		return renderText(event, width)
+		return renderText(event, o)
 	case "thinking":
-		return renderThinking(event, width)
+		return renderThinking(event, o)
 	case "tool_result":
-		return renderToolResult(event, width)
+		return renderToolResult(event, o)
 	case "user":
-		return renderUser(event, width)
+		return renderUser(event, o)
 	case "result":
-		return renderResult(event, width)
+		return renderResult(event, o)
+	case "agent":
+		return renderAgent(event, o)
+	case "fork":
+		return renderFork(event, o)
+	case "compact":
+		return renderCompact(event, o)
+	case "summary":
+		return renderSummary(event, o)
+	case "unknown":
+		return renderUnknownBlock(event, o)
 	default:
-		return renderUnknown(event, width)
+		return renderUnknown(event, o)
 	}
 }
 
-func renderSystem(event *model.DisplayEvent, width int) string {
+func renderSystem(event *model.DisplayEvent, o renderOpts) string {
 	if event.Text != "" {
-		contentWidth := width - 4 // account for padding
-		return eventStyle.Width(width).Render(usageStyle.Width(contentWidth).Render(event.Text))
+		contentWidth := o.width - 4 // account for padding
+		return eventStyle.Width(o.width).Render(usageStyle.Width(contentWidth).Render(event.Text))
 	}
 	return ""
 }
 
-func renderText(event *model.DisplayEvent, width int) string {
-	text := event.Text
-	if len(text) > 300 {
-		text = text[:300] + "..."
+func renderText(event *model.DisplayEvent, o renderOpts) string {
+	if event.Partial && event.Text == "" {
+		return "" // streaming hasn't produced any text yet
 	}
-	text = strings.TrimSpace(text)
-	lines := strings.Split(text, "\n")
-	if len(lines) > 5 {
-		lines = lines[:5]
-		lines = append(lines, "...")
+	contentWidth := o.width - 4
+	lines := renderMarkdown(event.Text, contentWidth, o.highlight)
+	if event.Partial && len(lines) > 0 {
+		// Follow the tail of text that is still streaming in
+		if !o.expanded && len(lines) > 8 {
+			lines = append([]string{"..."}, lines[len(lines)-8:]...)
+		}
+		lines[len(lines)-1] += cursorStyle.Render("▍")
+	} else {
+		lines = o.clipLines(lines, 8)
 	}
-	text = strings.Join(lines, "\n")
-	contentWidth := width - 4
-	return eventStyle.Width(width).Render(textStyle.Width(contentWidth).Render(text))
+	return eventStyle.Width(o.width).Render(strings.Join(lines, "\n"))
 }
 
-func renderThinking(event *model.DisplayEvent, width int) string {
-	text := event.Text
-	if len(text) > 200 {
-		text = text[:200] + "..."
+func renderThinking(event *model.DisplayEvent, o renderOpts) string {
+	if event.Subtype == "redacted" {
+		return eventStyle.Width(o.width).Render(usageStyle.Render("✱ thinking redacted"))
 	}
+	text := o.truncate(event.Text, 200)
 	text = strings.TrimSpace(text)
-	contentWidth := width - 4
-	return eventStyle.Width(width).Render(thinkingStyle.Width(contentWidth).Render(text))
+	contentWidth := o.width - 4
+	return eventStyle.Width(o.width).Render(thinkingStyle.Width(contentWidth).Render(text))
 }
 
-func renderToolUse(event *model.DisplayEvent, width int) string {
+func renderToolUse(event *model.DisplayEvent, o renderOpts) string {
 	tool := event.ToolUse
 	if tool == nil {
 		return ""
 	}
 
-	toolName := toolNameStyle.Render("● " + tool.Name)
-	contentWidth := width - 6
+	toolName := toolNameStyle.Render("● "+tool.Name) + " " + renderToolStatus(tool)
+	contentWidth := o.width - 6
+
+	body := renderToolInput(ToolInput{
+		Name:      tool.Name,
+		Input:     tool.Input,
+		Width:     contentWidth,
+		Expanded:  o.expanded,
+		Highlight: o.highlight,
+		tool:      tool,
+		cached:    o.cached,
+	})
 
-	// Special rendering for TodoWrite
-	if tool.Name == "TodoWrite" {
-		todoContent := renderTodoWriteInput(tool.Input, contentWidth)
-		if todoContent != "" {
-			return eventStyle.Width(width).Render(fmt.Sprintf("%s\n%s", toolName, todoContent))
+	if tool.Agent != nil {
+		body += "\n" + renderAgentRun(tool.Agent, tool, renderOpts{width: contentWidth, expanded: o.expanded, highlight: o.highlight, hideToolOutput: o.hideToolOutput, graphics: o.graphics, cached: o.cached})
+	}
+	if tool.Result != nil && !o.hideToolOutput {
+		lang := ""
+		if tool.Name == "Read" && o.highlight {
+			lang = detectLanguage(toolFilePath(tool.Input))
+		}
+		if output := renderToolOutput(tool.Result.Content, contentWidth, o, lang, tool.Failed()); output != "" {
+			body += "\n" + output
 		}
+		if len(tool.Result.Attachments) > 0 {
+			body += "\n" + renderAttachments(tool.Result.Attachments, o)
+		}
+	}
+	return eventStyle.Width(o.width).Render(fmt.Sprintf("%s\n%s", toolName, body))
+}
+
+// renderToolStatus renders the state of a tool call: running or done with latency
+func renderToolStatus(tool *model.ToolUse) string {
+	if tool.Pending() {
+		return usageStyle.Render("… running")
+	}
+	status, style := "✓", successStyle
+	if tool.Failed() {
+		status, style = "✗", errorStyle
 	}
+	if latency := tool.Latency(); latency > 0 {
+		status += " " + formatLatency(latency)
+	}
+	return style.Render(status)
+}
 
-	input := tool.Input
-	if len(input) > 150 {
-		input = input[:150] + "..."
+// formatLatency formats a tool call duration compactly
+func formatLatency(d time.Duration) string {
+	if d < time.Second {
+		return fmt.Sprintf("%dms", d.Milliseconds())
 	}
-	return eventStyle.Width(width).Render(fmt.Sprintf("%s\n  %s", toolName, toolInputStyle.Width(contentWidth).Render(input)))
+	if d < time.Minute {
+		return fmt.Sprintf("%.1fs", d.Seconds())
+	}
+	return fmt.Sprintf("%.1fmin", d.Minutes())
 }
 
 // todoItem represents a single todo from TodoWrite input
@@ -132,64 +220,154 @@ func renderTodoWriteInput(input string, width int) string {
 	return strings.Join(lines, "\n")
 }
 
-func renderUser(event *model.DisplayEvent, width int) string {
-	text := event.Text
-	if len(text) > 200 {
-		text = text[:200] + "..."
-	}
+func renderUser(event *model.DisplayEvent, o renderOpts) string {
+	text := o.truncate(event.Text, 200)
 	text = strings.TrimSpace(text)
-	contentWidth := width - 6
-	return eventStyle.Width(width).Render(fmt.Sprintf("> %s", textStyle.Width(contentWidth).Render(text)))
+	contentWidth := o.width - 6
+	out := fmt.Sprintf("> %s", textStyle.Width(contentWidth).Render(text))
+	if len(event.Attachments) > 0 {
+		out += "\n" + renderAttachments(event.Attachments, o)
+	}
+	return eventStyle.Width(o.width).Render(out)
 }
 
-func renderToolResult(event *model.DisplayEvent, width int) string {
+func renderToolResult(event *model.DisplayEvent, o renderOpts) string {
 	if event.ToolResult == nil {
 		return ""
 	}
 
-	content := event.ToolResult.Content
-	if len(content) > 200 {
-		content = content[:200] + "..."
+	contentWidth := o.width - 6
+	out := renderToolOutput(event.ToolResult.Content, contentWidth, o, "", event.ToolResult.IsError)
+	if attachments := event.ToolResult.Attachments; len(attachments) > 0 {
+		out = strings.TrimPrefix(out+"\n"+renderAttachments(attachments, o), "\n")
 	}
-	lines := strings.Split(content, "\n")
-	if len(lines) > 4 {
-		lines = lines[:4]
-		lines = append(lines, "...")
+	return eventStyle.Width(o.width).Render(out)
+}
+
+// renderToolOutput renders tool result content, shared by paired tool calls
+// and orphan results. Errors are shown in the error color; other content in
+// a known language is highlighted.
+func renderToolOutput(content string, width int, o renderOpts, lang string, isError bool) string {
+	if content == "" {
+		return ""
+	}
+	style := resultStyle
+	if isError {
+		style = errorStyle
+	} else if h := newHighlighter(lang); h != nil {
+		// The clip marker is added after highlighting so it isn't styled as code
+		all := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+		shown := all
+		if !o.expanded && len(shown) > 4 {
+			shown = shown[:4]
+		}
+		lines := highlightCode(shown, h, width)
+		for i, line := range lines {
+			lines[i] = "  " + line
+		}
+		if len(shown) < len(all) {
+			lines = append(lines, "  ...")
+		}
+		return strings.Join(lines, "\n")
 	}
+	content = o.truncate(content, 200)
+	lines := o.clipLines(strings.Split(content, "\n"), 4)
 	content = strings.Join(lines, "\n  ")
-	contentWidth := width - 6
-	return eventStyle.Width(width).Render(fmt.Sprintf("  %s", resultStyle.Width(contentWidth).Render(content)))
+	return fmt.Sprintf("  %s", style.Width(width).Render(content))
+}
+
+// toolFilePath returns the file_path of a tool input, if any
+func toolFilePath(input string) string {
+	var data struct {
+		FilePath string `json:"file_path"`
+	}
+	json.Unmarshal([]byte(input), &data)
+	return data.FilePath
+}
+
+func renderResult(event *model.DisplayEvent, o renderOpts) string {
+	contentWidth := o.width - 4
+	if event.IsError {
+		badge := badgeError.Render("ERROR")
+		text := o.truncate(event.Text, 300)
+		return eventStyle.Width(o.width).Render(badge + "\n" + errorStyle.Width(contentWidth).Render(text))
+	}
+	return eventStyle.Width(o.width).Render(successStyle.Width(contentWidth).Render("✓ " + event.Text))
+}
+
+// renderCompact renders a compaction as a divider with the context size it
+// started from and the summary that replaced the history
+func renderCompact(event *model.DisplayEvent, o renderOpts) string {
+	label := "⊟ compacted"
+	if event.Subtype != "" {
+		label += " (" + event.Subtype + ")"
+	}
+	if event.PreTokens > 0 {
+		label += " · " + formatTokens(event.PreTokens) + " tokens"
+	}
+	label += " "
+	rule := strings.Repeat("─", max(o.width-4-ansi.StringWidth(label)-3, 0))
+	out := compactStyle.Render("── " + label + rule)
+	if text := strings.TrimSpace(event.Text); text != "" {
+		contentWidth := o.width - 4
+		lines := o.clipLines(strings.Split(o.truncate(text, 300), "\n"), 4)
+		out += "\n" + usageStyle.Width(contentWidth).Render(strings.Join(lines, "\n"))
+	}
+	return eventStyle.Width(o.width).Render(out)
 }
 
-func renderResult(event *model.DisplayEvent, width int) string {
-	contentWidth := width - 4
-	return eventStyle.Width(width).Render(successStyle.Width(contentWidth).Render("✓ " + event.Text))
+// renderSummary renders a summary line naming the conversation
+func renderSummary(event *model.DisplayEvent, o renderOpts) string {
+	contentWidth := o.width - 4
+	return eventStyle.Width(o.width).Render(compactStyle.Width(contentWidth).Render("◆ " + event.Text))
 }
 
-func renderUnknown(event *model.DisplayEvent, width int) string {
+// renderUnknownBlock renders a placeholder for a content block clancy can't
+// display, naming its type
+func renderUnknownBlock(event *model.DisplayEvent, o renderOpts) string {
+	return eventStyle.Width(o.width).Render(usageStyle.Render("◇ " + event.Subtype + " block"))
+}
+
+func renderUnknown(event *model.DisplayEvent, o renderOpts) string {
 	if event.Text != "" {
-		text := event.Text
-		if len(text) > 100 {
-			text = text[:100] + "..."
-		}
-		contentWidth := width - 4
-		return eventStyle.Width(width).Render(textStyle.Width(contentWidth).Render(text))
+		text := o.truncate(event.Text, 100)
+		contentWidth := o.width - 4
+		return eventStyle.Width(o.width).Render(textStyle.Width(contentWidth).Render(text))
 	}
 	return ""
 }
 
-// renderStatusBar renders the top status bar
-func renderStatusBar(filename string, eventCount int, width int) string {
-	left := fmt.Sprintf(" watching: %s", filename)
-	right := fmt.Sprintf("%d events ", eventCount)
-	spaces := width - len(left) - len(right)
+// renderStatusBar renders the top status bar. Extra segments such as the
+// search hit counter are shown on the right, before the event count.
+func renderStatusBar(filename string, eventCount int, width int, extras ...string) string {
+	var right []string
+	for _, extra := range extras {
+		if extra != "" {
+			right = append(right, extra)
+		}
+	}
+	right = append(right, fmt.Sprintf("%d events ", eventCount))
+	rightStr := strings.Join(right, " | ")
+
+	// The file name gives way first when the bar is too narrow
+	inner := width - statusBarStyle.GetHorizontalFrameSize()
+	left := ansi.Truncate(fmt.Sprintf(" watching: %s", filename), max(inner-ansi.StringWidth(rightStr)-1, 0), "…")
+	spaces := inner - ansi.StringWidth(left) - ansi.StringWidth(rightStr)
 	if spaces < 1 {
 		spaces = 1
 	}
-	return statusBarStyle.Width(width).Render(left + strings.Repeat(" ", spaces) + right)
+	return renderBar(statusBarStyle, left+strings.Repeat(" ", spaces)+rightStr, width)
 }
 
-// renderHelpBar renders the bottom help bar
+// renderBar renders a status or help bar on a single line, cutting content
+// that doesn't fit rather than letting it wrap
+func renderBar(style lipgloss.Style, content string, width int) string {
+	inner := max(width-style.GetHorizontalFrameSize(), 0)
+	return style.Width(width).Render(ansi.Truncate(content, inner, "…"))
+}
+
+// renderHelpBar renders the bottom help bar. The follow indicator leads so
+// it stays visible when the key list is cut to the terminal width.
 func renderHelpBar(followMode bool, width int) string {
 	followIndicator := ""
 	if followMode {
@@ -197,6 +375,11 @@ func renderHelpBar(followMode bool, width int) string {
 	} else {
 		followIndicator = followOffStyle.Render("[follow off]")
 	}
-	help := fmt.Sprintf("q:quit  ↑↓/jk:scroll  g/G:top/bottom  f:follow  %s", followIndicator)
-	return helpBarStyle.Width(width).Render(help)
+	help := fmt.Sprintf("%s  q:quit  ↑↓/jk:scroll  JK:select  enter:expand  +/-:all  /:search  n/N:next/prev  t/o/x:thinking/output/errors  e/E:errors  c/C:compactions  b/B:branches  H:highlight  w:save image  s:stats  d:diagnostics  p:sessions  tab/1-9:tabs  ^w:close  g/G:top/bottom  f:follow", followIndicator)
+	return renderBar(helpBarStyle, help, width)
+}
+
+// renderSearchPrompt renders the search input line in place of the help bar
+func renderSearchPrompt(query string, width int) string {
+	return renderBar(helpBarStyle, "/"+query+cursorStyle.Render("█"), width)
 }
@@ -1,10 +1,13 @@
 package ui
 
 import (
+	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/aquila/clancy/model"
+	"github.com/charmbracelet/x/ansi"
 )
 
 func TestRenderTextWrapsLongLines(t *testing.T) {
@@ -16,7 +19,7 @@ func TestRenderTextWrapsLongLines(t *testing.T) {
 	}
 
 	width := 40
-	result := renderText(event, width)
+	result := renderText(event, renderOpts{width: width})
 
 	lines := strings.Split(result, "\n")
 	for i, line := range lines {
@@ -42,7 +45,7 @@ func TestRenderToolUseWrapsInput(t *testing.T) {
 	}
 
 	width := 50
-	result := renderToolUse(event, width)
+	result := renderToolUse(event, renderOpts{width: width})
 
 	if result == "" {
 		t.Error("expected non-empty result")
@@ -62,7 +65,7 @@ func TestRenderUserWrapsText(t *testing.T) {
 	}
 
 	width := 40
-	result := renderUser(event, width)
+	result := renderUser(event, renderOpts{width: width})
 
 	if result == "" {
 		t.Error("expected non-empty result")
@@ -81,7 +84,7 @@ func TestRenderThinkingWrapsText(t *testing.T) {
 	}
 
 	width := 40
-	result := renderThinking(event, width)
+	result := renderThinking(event, renderOpts{width: width})
 
 	if result == "" {
 		t.Error("expected non-empty result")
@@ -98,7 +101,7 @@ func TestRenderToolResultWrapsContent(t *testing.T) {
 	}
 
 	width := 50
-	result := renderToolResult(event, width)
+	result := renderToolResult(event, renderOpts{width: width})
 
 	if result == "" {
 		t.Error("expected non-empty result")
@@ -120,7 +123,7 @@ func TestRenderEventDispatchesCorrectly(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := renderEvent(tt.event, 80)
+			result := renderEvent(tt.event, renderOpts{width: 80})
 			if result == "" && tt.event.Text != "" {
 				t.Errorf("expected non-empty result for %s", tt.name)
 			}
@@ -196,7 +199,7 @@ func TestRenderToolUseTodoWrite(t *testing.T) {
 		},
 	}
 
-	result := renderToolUse(event, 80)
+	result := renderToolUse(event, renderOpts{width: 80})
 
 	if !strings.Contains(result, "TodoWrite") {
 		t.Error("expected tool name in output")
@@ -225,7 +228,7 @@ func TestRenderToolUseTodoWriteFallback(t *testing.T) {
 		},
 	}
 
-	result := renderToolUse(event, 80)
+	result := renderToolUse(event, renderOpts{width: 80})
 
 	if !strings.Contains(result, "TodoWrite") {
 		t.Error("expected tool name in output")
@@ -235,3 +238,136 @@ func TestRenderToolUseTodoWriteFallback(t *testing.T) {
 		t.Error("expected fallback to raw input")
 	}
 }
+
+func TestRenderToolUseWithResult(t *testing.T) {
+	start := time.Date(2025, 1, 10, 10, 0, 0, 0, time.UTC)
+	tool := &model.ToolUse{
+		ID:        "toolu_1",
+		Name:      "Bash",
+		Input:     `{"command":"go test ./..."}`,
+		StartedAt: start,
+	}
+	event := &model.DisplayEvent{Type: "assistant", ToolUse: tool}
+
+	pending := renderToolUse(event, renderOpts{width: 80})
+	if !strings.Contains(pending, "running") {
+		t.Error("expected pending tool call to show running state")
+	}
+
+	tool.Result = &model.ToolResult{
+		ToolUseID:   "toolu_1",
+		Content:     "ok  	github.com/aquila/clancy",
+		CompletedAt: start.Add(2300 * time.Millisecond),
+	}
+	done := renderToolUse(event, renderOpts{width: 80})
+	if !strings.Contains(done, "✓ 2.3s") {
+		t.Error("expected completed tool call to show latency")
+	}
+	if !strings.Contains(done, "github.com/aquila/clancy") {
+		t.Error("expected tool output under the call")
+	}
+}
+
+func TestRenderTextExpandedShowsFullContent(t *testing.T) {
+	var lines []string
+	for i := 0; i < 10; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	event := &model.DisplayEvent{Type: "assistant", Text: strings.Join(lines, "\n")}
+
+	collapsed := renderText(event, renderOpts{width: 80})
+	if strings.Contains(collapsed, "line 9") {
+		t.Error("expected collapsed text to be clipped")
+	}
+
+	expanded := renderText(event, renderOpts{width: 80, expanded: true})
+	if !strings.Contains(expanded, "line 9") {
+		t.Error("expected expanded text to show every line")
+	}
+}
+
+func TestRenderTextStreamingFollowsTail(t *testing.T) {
+	var lines []string
+	for i := 0; i < 10; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	event := &model.DisplayEvent{Type: "assistant", Text: strings.Join(lines, "\n"), Partial: true}
+
+	got := renderText(event, renderOpts{width: 80})
+	if !strings.Contains(got, "line 9▍") || strings.Contains(got, "line 0") {
+		t.Errorf("expected streaming text to show its tail with a cursor:\n%s", got)
+	}
+
+	if renderText(&model.DisplayEvent{Type: "assistant", Partial: true}, renderOpts{width: 80}) != "" {
+		t.Error("expected empty streaming text to render nothing")
+	}
+}
+
+func TestRenderTaskNestsSubagentRun(t *testing.T) {
+	start := time.Date(2025, 1, 10, 10, 0, 0, 0, time.UTC)
+	agent := &model.Agent{
+		ID: "c0ffee0012345678",
+		Events: []*model.DisplayEvent{
+			{Type: "user", Text: "List the test files"},
+			{Type: "assistant", Text: "Found 3 files"},
+		},
+		Messages: []*model.AssistantMessage{{MessageUsage: model.MessageUsage{Usage: model.Usage{InputTokens: 1500, OutputTokens: 200}}}},
+		Start:    start,
+		End:      start.Add(4 * time.Second),
+	}
+	event := &model.DisplayEvent{
+		Type: "assistant",
+		ToolUse: &model.ToolUse{
+			ID:    "toolu_task",
+			Name:  "Task",
+			Input: `{"description":"find tests","prompt":"List the test files"}`,
+			Agent: agent,
+		},
+	}
+
+	collapsed := renderToolUse(event, renderOpts{width: 100})
+	if !strings.Contains(collapsed, "▸ subagent c0ffee00 2 events · ↑1.5k ↓200 · 4.0s · running") {
+		t.Errorf("expected run summary:\n%s", collapsed)
+	}
+	if strings.Contains(collapsed, "Found 3 files") {
+		t.Errorf("expected nested events hidden while collapsed:\n%s", collapsed)
+	}
+
+	expanded := renderToolUse(event, renderOpts{width: 100, expanded: true})
+	if !strings.Contains(expanded, "│") || !strings.Contains(expanded, "Found 3 files") {
+		t.Errorf("expected nested events when expanded:\n%s", expanded)
+	}
+}
+
+func TestRenderRedactedThinkingAndUnknownBlocks(t *testing.T) {
+	redacted := renderEvent(&model.DisplayEvent{Type: "thinking", Subtype: "redacted"}, renderOpts{width: 80})
+	if !strings.Contains(redacted, "thinking redacted") {
+		t.Errorf("expected a redaction marker:\n%s", redacted)
+	}
+	unknown := renderEvent(&model.DisplayEvent{Type: "unknown", Subtype: "container_upload", Text: "container_upload block"}, renderOpts{width: 80})
+	if !strings.Contains(unknown, "◇ container_upload block") {
+		t.Errorf("expected the block type in the fallback:\n%s", unknown)
+	}
+	search := renderEvent(&model.DisplayEvent{Type: "assistant", ToolUse: &model.ToolUse{
+		Name:   "web_search",
+		Input:  `{"query":"bubbletea images"}`,
+		Result: &model.ToolResult{Content: "Bubble Tea\nhttps://github.com/charmbracelet/bubbletea"},
+	}}, renderOpts{width: 80})
+	for _, want := range []string{`"bubbletea images"`, "Bubble Tea", "https://github.com/charmbracelet/bubbletea"} {
+		if !strings.Contains(search, want) {
+			t.Errorf("expected %q in web search call:\n%s", want, search)
+		}
+	}
+}
+
+func TestHelpBarKeepsFollowIndicator(t *testing.T) {
+	for _, follow := range []bool{true, false} {
+		want := "[FOLLOW]"
+		if !follow {
+			want = "[follow off]"
+		}
+		if bar := ansi.Strip(renderHelpBar(follow, 80)); !strings.Contains(bar, want) {
+			t.Errorf("expected %q in an 80 column help bar, got %q", want, bar)
+		}
+	}
+}
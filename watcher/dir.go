@@ -0,0 +1,85 @@
+package watcher
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// DirWatcher reports session transcripts created in a Claude Code project
+// directory, e.g. after /clear or when a new session starts
+type DirWatcher struct {
+	dir   string
+	files chan string
+	done  chan struct{}
+}
+
+// NewDir creates a watcher for new session files in dir
+func NewDir(dir string) *DirWatcher {
+	return &DirWatcher{
+		dir:   dir,
+		files: make(chan string, 10),
+		done:  make(chan struct{}),
+	}
+}
+
+// Files returns the channel of newly created session file paths
+func (d *DirWatcher) Files() <-chan string {
+	return d.files
+}
+
+// Start begins watching the directory
+func (d *DirWatcher) Start() error {
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	if err := fw.Add(d.dir); err != nil {
+		fw.Close()
+		return err
+	}
+	go d.watch(fw)
+	return nil
+}
+
+// Stop stops watching the directory
+func (d *DirWatcher) Stop() {
+	close(d.done)
+}
+
+func (d *DirWatcher) watch(fw *fsnotify.Watcher) {
+	defer close(d.files)
+	defer fw.Close()
+
+	for {
+		select {
+		case <-d.done:
+			return
+
+		case event, ok := <-fw.Events:
+			if !ok {
+				return
+			}
+			if event.Op&fsnotify.Create == fsnotify.Create && isSessionFile(event.Name) {
+				select {
+				case d.files <- event.Name:
+				case <-d.done:
+					return
+				}
+			}
+
+		case _, ok := <-fw.Errors:
+			if !ok {
+				return
+			}
+		}
+	}
+}
+
+// isSessionFile reports whether a path is a main session transcript. Older
+// Claude Code versions write subagent sidechains next to it as agent-*.jsonl.
+func isSessionFile(path string) bool {
+	name := filepath.Base(path)
+	return strings.HasSuffix(name, ".jsonl") && !strings.HasPrefix(name, "agent-")
+}
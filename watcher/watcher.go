@@ -2,33 +2,125 @@ package watcher
 
 import (
 	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
 	"io"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
 )
 
-// Watcher performs tail -f on a JSONL file
+// idleAfter is how long a session goes without new lines before it counts
+// as idle rather than running
+const idleAfter = 10 * time.Second
+
+// Phase is the lifecycle state of a watched session
+type Phase int
+
+const (
+	Running Phase = iota
+	Idle
+	Finished
+)
+
+// Status describes the state of a watched session
+type Status struct {
+	Phase        Phase
+	LastActivity time.Time // when the last line arrived
+	Reason       string    // why the session finished, e.g. "file removed"
+}
+
+// String renders the status for display, e.g. "running", "idle for 2m" or
+// "finished"
+func (s Status) String() string {
+	switch s.Phase {
+	case Running:
+		return "running"
+	case Idle:
+		return "idle for " + formatIdle(time.Since(s.LastActivity))
+	}
+	if s.Reason == "" || s.Reason == "result" {
+		return "finished"
+	}
+	return "finished (" + s.Reason + ")"
+}
+
+// formatIdle formats an idle duration in its largest whole unit
+func formatIdle(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dd", int(d.Hours()/24))
+}
+
+// Line is a line read from the transcript, with where it starts so problems
+// with it can be located
+type Line struct {
+	Data   []byte
+	Number int   // 1-based, counting blank lines
+	Offset int64 // byte offset of the line's start
+
+	// Reset carries no data. It means the file was truncated or removed and
+	// recreated, and is read again from the start, so lines seen before it
+	// are stale.
+	Reset bool
+}
+
+// Watcher performs tail -f on a JSONL file, or reads lines from a stream
+// such as stdin until it ends
 type Watcher struct {
+	// IdleTimeout marks the session finished after this long without new
+	// lines. Zero disables it. Set it before calling Start.
+	IdleTimeout time.Duration
+
 	filePath string
-	lines    chan []byte
+	reader   io.Reader // set when reading a stream instead of a file
+	lines    chan Line
 	errors   chan error
 	done     chan struct{}
+
+	// Only touched by the reading goroutine
+	number  int    // lines read so far
+	lastErr string // latest error reported, so a retry loop reports it once
+
+	mu           sync.Mutex
+	lastActivity time.Time
+	endReason    string // set once the session is known to have ended
 }
 
 // New creates a new file watcher
 func New(filePath string) *Watcher {
 	return &Watcher{
 		filePath: filePath,
-		lines:    make(chan []byte, 100),
-		errors:   make(chan error, 1),
+		lines:    make(chan Line, 100),
+		errors:   make(chan error, 10),
 		done:     make(chan struct{}),
 	}
 }
 
+// NewReader creates a watcher that reads lines from r, e.g. the end of a
+// pipe. The lines channel is closed when r is exhausted.
+func NewReader(r io.Reader) *Watcher {
+	w := New("")
+	w.reader = r
+	return w
+}
+
+// Path returns the watched file, or "" for a stream
+func (w *Watcher) Path() string {
+	return w.filePath
+}
+
 // Lines returns the channel for new lines
-func (w *Watcher) Lines() <-chan []byte {
+func (w *Watcher) Lines() <-chan Line {
 	return w.lines
 }
 
@@ -39,15 +131,99 @@ func (w *Watcher) Errors() <-chan error {
 
 // Start begins watching the file
 func (w *Watcher) Start() error {
+	if w.reader != nil {
+		w.lastActivity = time.Now()
+		go w.readStream()
+		return nil
+	}
+
 	// Verify file exists
-	if _, err := os.Stat(w.filePath); err != nil {
+	info, err := os.Stat(w.filePath)
+	if err != nil {
 		return err
 	}
+	w.lastActivity = info.ModTime()
 
 	go w.watch()
 	return nil
 }
 
+// Status returns the current state of the session
+func (w *Watcher) Status() Status {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	s := Status{LastActivity: w.lastActivity}
+	idle := time.Since(w.lastActivity)
+	switch {
+	case w.endReason != "":
+		s.Phase, s.Reason = Finished, w.endReason
+	case w.IdleTimeout > 0 && idle >= w.IdleTimeout:
+		s.Phase, s.Reason = Finished, "idle timeout"
+	case idle >= idleAfter:
+		s.Phase = Idle
+	default:
+		s.Phase = Running
+	}
+	return s
+}
+
+// noteLine records a line written at the given time. A result event ends
+// the session; any later line means it was resumed.
+func (w *Watcher) noteLine(line []byte, at time.Time) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if at.After(w.lastActivity) {
+		w.lastActivity = at
+	}
+	w.endReason = ""
+	if isResult(line) {
+		w.endReason = "result"
+	}
+}
+
+// finish marks the session ended for the given reason
+func (w *Watcher) finish(reason string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.endReason = reason
+}
+
+// isResult reports whether a line is a top-level result event
+func isResult(line []byte) bool {
+	if !bytes.Contains(line, []byte(`"result"`)) {
+		return false // cheap check before decoding
+	}
+	var event struct {
+		Type string `json:"type"`
+	}
+	return json.Unmarshal(line, &event) == nil && event.Type == "result"
+}
+
+// report sends an error to the Errors channel unless it repeats the previous
+// one. Errors are dropped when nobody is reading them.
+func (w *Watcher) report(err error) {
+	if err.Error() == w.lastErr {
+		return
+	}
+	w.lastErr = err.Error()
+	select {
+	case w.errors <- err:
+	default:
+	}
+}
+
+// reset tells the reader the file starts over. Returns false if done was
+// closed first.
+func (w *Watcher) reset() bool {
+	select {
+	case w.lines <- Line{Reset: true}:
+		return true
+	case <-w.done:
+		return false
+	}
+}
+
 // Stop stops watching the file
 func (w *Watcher) Stop() {
 	close(w.done)
@@ -59,142 +235,228 @@ func (w *Watcher) watch() {
 	var offset int64 = 0
 
 	for {
-		// Try to watch with fsnotify
-		var shouldRetry bool
-		shouldRetry, offset = w.watchWithFsnotify(offset)
-		if shouldRetry {
-			// Session ended - wait for new session
-			if !w.waitForNewSession() {
-				return // done was closed
-			}
-		} else {
+		var ok bool
+		if offset, ok = w.follow(offset); !ok {
 			return // done was closed
 		}
+
+		// The file was removed or renamed. If something replaced it, such as
+		// an atomic save, follow the new file; otherwise the session is over
+		// until the file comes back.
+		if !w.exists() {
+			w.finish("file removed")
+			if !w.waitForFile() {
+				return
+			}
+			if offset > 0 && !w.reset() {
+				return
+			}
+			offset, w.number = 0, 0
+			continue
+		}
+		if !w.sleep(100 * time.Millisecond) {
+			return
+		}
 	}
 }
 
-// watchWithFsnotify watches file using fsnotify. Returns (shouldRetry, newOffset).
-func (w *Watcher) watchWithFsnotify(offset int64) (bool, int64) {
+// follow tails the file from offset until it is removed or renamed, or the
+// watcher stops. It returns the new offset and false once done is closed.
+func (w *Watcher) follow(offset int64) (int64, bool) {
 	file, err := os.Open(w.filePath)
 	if err != nil {
-		return true, offset
+		if !os.IsNotExist(err) {
+			w.report(err) // a missing file is handled by the caller
+		}
+		return offset, true
 	}
 	defer file.Close()
 
 	// Check if file was truncated (new session with fresh file)
 	info, err := file.Stat()
 	if err != nil {
-		return true, offset
+		w.report(err)
+		return offset, true
 	}
 	if info.Size() < offset {
-		offset = 0 // File truncated, start from beginning
+		// File truncated, start from beginning
+		if !w.reset() {
+			return offset, false
+		}
+		offset, w.number = 0, 0
 	}
 
 	// Seek to offset
 	if _, err := file.Seek(offset, io.SeekStart); err != nil {
-		return true, offset
+		w.report(err)
+		return offset, true
 	}
 
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
-		return true, offset
+		w.report(fmt.Errorf("watching %s: %w", w.filePath, err))
+		return offset, true
 	}
 	defer watcher.Close()
 
 	if err := watcher.Add(w.filePath); err != nil {
-		return true, offset
+		if !os.IsNotExist(err) {
+			w.report(fmt.Errorf("watching %s: %w", w.filePath, err))
+		}
+		return offset, true
 	}
 
 	reader := bufio.NewReader(file)
 
 	// Read available content from offset
-	offset = w.readAvailable(reader, offset)
+	offset = w.readAvailable(file, reader, offset)
 
-	// Idle timeout to detect session end
-	idleTimeout := time.NewTimer(3 * time.Second)
-	defer idleTimeout.Stop()
+	// Some filesystems drop change events, so poll slowly as a fallback
+	poll := time.NewTicker(time.Second)
+	defer poll.Stop()
 
 	for {
 		select {
 		case <-w.done:
-			return false, offset
+			return offset, false
 
-		case <-idleTimeout.C:
-			// No activity for 3 seconds, session likely ended
-			return true, offset
+		case <-poll.C:
+			offset = w.readAvailable(file, reader, offset)
+			if !w.exists() {
+				return offset, true
+			}
 
 		case event, ok := <-watcher.Events:
 			if !ok {
-				return true, offset
+				return offset, true
 			}
 			if event.Op&fsnotify.Write == fsnotify.Write {
-				offset = w.readAvailable(reader, offset)
-				// Reset idle timeout on activity
-				if !idleTimeout.Stop() {
-					select {
-					case <-idleTimeout.C:
-					default:
-					}
-				}
-				idleTimeout.Reset(3 * time.Second)
+				offset = w.readAvailable(file, reader, offset)
 			}
 			if event.Op&fsnotify.Remove == fsnotify.Remove || event.Op&fsnotify.Rename == fsnotify.Rename {
-				return true, offset
+				return offset, true
+			}
+			// Unlinking a file we hold open only shows up as a change of its
+			// link count
+			if event.Op&fsnotify.Chmod == fsnotify.Chmod && !w.exists() {
+				return offset, true
 			}
 
-		case _, ok := <-watcher.Errors:
+		case err, ok := <-watcher.Errors:
 			if !ok {
-				return true, offset
+				return offset, true
 			}
+			w.report(fmt.Errorf("watching %s: %w", w.filePath, err))
 		}
 	}
 }
 
-// waitForNewSession polls until file changes (new content or new file). Returns false if done.
-func (w *Watcher) waitForNewSession() bool {
-	ticker := time.NewTicker(100 * time.Millisecond)
-	defer ticker.Stop()
+// exists reports whether the watched path exists
+func (w *Watcher) exists() bool {
+	_, err := os.Stat(w.filePath)
+	return err == nil
+}
 
-	// Get current file info
-	var lastSize int64 = -1
-	var lastMod time.Time
-	if info, err := os.Stat(w.filePath); err == nil {
-		lastSize = info.Size()
-		lastMod = info.ModTime()
-	}
+// waitForFile polls until the file exists again. Returns false if done.
+func (w *Watcher) waitForFile() bool {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
 
 	for {
 		select {
 		case <-w.done:
 			return false
 		case <-ticker.C:
-			info, err := os.Stat(w.filePath)
-			if err != nil {
-				// File removed, wait for it to reappear
-				lastSize = -1
-				continue
-			}
-			// Check if file changed (new session started)
-			if info.Size() != lastSize || info.ModTime() != lastMod {
+			if w.exists() {
 				return true
 			}
 		}
 	}
 }
 
-func (w *Watcher) readAvailable(reader *bufio.Reader, offset int64) int64 {
+// sleep waits for d. Returns false if done was closed first.
+func (w *Watcher) sleep(d time.Duration) bool {
+	select {
+	case <-w.done:
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+// readStream emits lines from the reader until it ends. Unlike a file, a
+// stream can't be reopened, so a final line without a newline is kept.
+func (w *Watcher) readStream() {
+	defer close(w.lines)
+
+	reader := bufio.NewReader(w.reader)
+	var offset int64
 	for {
 		line, err := reader.ReadBytes('\n')
+		start := offset
+		offset += int64(len(line))
+		if len(line) > 0 {
+			w.number++
+		}
+		line = bytes.TrimRight(line, "\r\n")
+		if len(line) > 0 {
+			w.noteLine(line, time.Now())
+			select {
+			case w.lines <- Line{Data: line, Number: w.number, Offset: start}:
+			case <-w.done:
+				return
+			}
+		}
 		if err != nil {
-			if err == io.EOF {
-				// Partial line - don't emit, wait for newline
+			w.finish("end of stream")
+			if err != io.EOF {
+				w.report(err)
+			}
+			return
+		}
+	}
+}
+
+// readAvailable emits the complete lines written past offset and returns the
+// offset after the last one
+func (w *Watcher) readAvailable(file *os.File, reader *bufio.Reader, offset int64) int64 {
+	// Lines already in the file count as activity when it was last written,
+	// so an old transcript shows as idle rather than running
+	modTime := time.Now()
+	if info, err := file.Stat(); err == nil {
+		modTime = info.ModTime()
+		if info.Size() < offset {
+			// Truncated while we follow it, e.g. rewritten in place
+			if !w.reset() {
+				return offset
+			}
+			if _, err := file.Seek(0, io.SeekStart); err != nil {
+				w.report(err)
 				return offset
 			}
+			reader.Reset(file)
+			offset, w.number = 0, 0
+		}
+	}
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			if err == io.EOF && len(line) > 0 {
+				// Partial line - rewind so it's read again once complete
+				if _, err := file.Seek(offset, io.SeekStart); err == nil {
+					reader.Reset(file)
+				}
+			} else if err != io.EOF {
+				w.report(err)
+			}
 			return offset
 		}
 
 		// Update offset with bytes read
+		start := offset
 		offset += int64(len(line))
+		w.number++
+		w.lastErr = ""
 
 		// Trim newline
 		if len(line) > 0 && line[len(line)-1] == '\n' {
@@ -205,8 +467,9 @@ func (w *Watcher) readAvailable(reader *bufio.Reader, offset int64) int64 {
 		}
 
 		if len(line) > 0 {
+			w.noteLine(line, modTime)
 			select {
-			case w.lines <- line:
+			case w.lines <- Line{Data: line, Number: w.number, Offset: start}:
 			case <-w.done:
 				return offset
 			}
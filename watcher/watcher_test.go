@@ -0,0 +1,239 @@
+package watcher
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReaderEmitsLinesUntilEOF(t *testing.T) {
+	w := NewReader(strings.NewReader("{\"type\":\"system\"}\r\n\n{\"type\":\"user\"}\n{\"type\":\"result\"}"))
+	if err := w.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	var lines []string
+	for line := range w.Lines() {
+		lines = append(lines, string(line.Data))
+	}
+	want := []string{`{"type":"system"}`, `{"type":"user"}`, `{"type":"result"}`}
+	if strings.Join(lines, "|") != strings.Join(want, "|") {
+		t.Errorf("expected %q, got %q", want, lines)
+	}
+}
+
+func TestLinesCarryNumberAndOffset(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "s.jsonl")
+	if err := os.WriteFile(path, []byte("{\"type\":\"system\"}\r\n\n{\"type\":\"user\"}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	w := New(path)
+	if err := w.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	first, second := <-w.Lines(), <-w.Lines()
+	if first.Number != 1 || first.Offset != 0 {
+		t.Errorf("unexpected position of first line: %d @ %d", first.Number, first.Offset)
+	}
+	if string(second.Data) != `{"type":"user"}` || second.Number != 3 || second.Offset != 20 {
+		t.Errorf("expected the blank line counted, got %q at %d @ %d", second.Data, second.Number, second.Offset)
+	}
+
+	appendLine(t, path, `{"type":"result"}`)
+	if third := <-w.Lines(); third.Number != 4 || third.Offset != 36 {
+		t.Errorf("unexpected position of appended line: %d @ %d", third.Number, third.Offset)
+	}
+}
+
+// waitFor polls cond until it holds or the deadline passes
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestStatusFinishesOnResultAndResumes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "s.jsonl")
+	if err := os.WriteFile(path, []byte(`{"type":"user","message":{"role":"user","content":"hi"}}`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	w := New(path)
+	if err := w.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+	<-w.Lines()
+
+	if got := w.Status(); got.Phase != Running {
+		t.Errorf("expected a fresh file to be running, got %v", got)
+	}
+
+	appendLine(t, path, `{"type":"result","subtype":"success"}`)
+	<-w.Lines()
+	if got := w.Status(); got.Phase != Finished || got.String() != "finished" {
+		t.Errorf("expected finished after result, got %q", got)
+	}
+
+	appendLine(t, path, `{"type":"user","message":{"role":"user","content":"again"}}`)
+	<-w.Lines()
+	if got := w.Status(); got.Phase != Running {
+		t.Errorf("expected resumed session to be running, got %q", got)
+	}
+}
+
+func TestStatusFinishesWhenFileRemoved(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "s.jsonl")
+	if err := os.WriteFile(path, []byte("{}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	w := New(path)
+	if err := w.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+	<-w.Lines()
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "file removal", func() bool { return w.Status().Phase == Finished })
+	if got := w.Status().String(); got != "finished (file removed)" {
+		t.Errorf("unexpected status %q", got)
+	}
+}
+
+func TestStatusIdleAndTimeout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "s.jsonl")
+	if err := os.WriteFile(path, []byte("{}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * time.Minute)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	w := New(path)
+	if err := w.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+	<-w.Lines()
+	if got := w.Status().String(); got != "idle for 2m" {
+		t.Errorf("expected an old transcript to be idle, got %q", got)
+	}
+
+	w.mu.Lock()
+	w.IdleTimeout = time.Minute
+	w.mu.Unlock()
+	if got := w.Status(); got.Phase != Finished || got.Reason != "idle timeout" {
+		t.Errorf("expected idle timeout to finish the session, got %q", got)
+	}
+}
+
+func appendLine(t *testing.T, path, line string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(line + "\n"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDirWatcherReportsNewSessions(t *testing.T) {
+	dir := t.TempDir()
+	d := NewDir(dir)
+	if err := d.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer d.Stop()
+
+	for _, name := range []string{"agent-1a2b.jsonl", "notes.txt", "7f3a9c2e.jsonl"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	select {
+	case path := <-d.Files():
+		if filepath.Base(path) != "7f3a9c2e.jsonl" {
+			t.Errorf("expected only the session file, got %s", path)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for new session file")
+	}
+}
+
+// nextLine reads a line or fails after a timeout
+func nextLine(t *testing.T, w *Watcher) Line {
+	t.Helper()
+	select {
+	case line := <-w.Lines():
+		return line
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a line")
+	}
+	return Line{}
+}
+
+func TestRecreatedFileResetsLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "s.jsonl")
+	if err := os.WriteFile(path, []byte(`{"type":"user"}`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	w := New(path)
+	if err := w.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+	nextLine(t, w)
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "file removal", func() bool { return w.Status().Phase == Finished })
+	if err := os.WriteFile(path, []byte(`{"type":"system"}`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if reset := nextLine(t, w); !reset.Reset {
+		t.Fatalf("expected a reset before the file is read again, got %q", reset.Data)
+	}
+	if line := nextLine(t, w); string(line.Data) != `{"type":"system"}` || line.Number != 1 || line.Offset != 0 {
+		t.Errorf("expected the new file from its start, got %q at %d @ %d", line.Data, line.Number, line.Offset)
+	}
+}
+
+func TestTruncatedFileResetsLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "s.jsonl")
+	if err := os.WriteFile(path, []byte(`{"type":"user","message":{"role":"user","content":"a long first line"}}`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	w := New(path)
+	if err := w.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+	nextLine(t, w)
+
+	if err := os.WriteFile(path, []byte(`{"type":"system"}`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if reset := nextLine(t, w); !reset.Reset {
+		t.Fatalf("expected a reset after truncation, got %q", reset.Data)
+	}
+	if line := nextLine(t, w); string(line.Data) != `{"type":"system"}` || line.Number != 1 {
+		t.Errorf("expected the file read from its start, got %q at %d", line.Data, line.Number)
+	}
+}